@@ -0,0 +1,211 @@
+package fourten
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Backend is one of the base URLs a client can send requests to
+type Backend struct {
+	URL *url.URL
+
+	inFlight int32
+
+	mu           sync.Mutex
+	failures     int
+	ejectedUntil time.Time
+}
+
+// InFlight reports how many requests are currently being made to the backend
+func (b *Backend) InFlight() int {
+	return int(atomic.LoadInt32(&b.inFlight))
+}
+
+func (b *Backend) ejected(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.Before(b.ejectedUntil)
+}
+
+// record keeps track of consecutive failures, ejecting the backend once there have been too many
+func (b *Backend) record(failed bool, policy ejectionPolicy, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if policy.after > 0 && b.failures >= policy.after {
+		b.ejectedUntil = now.Add(policy.duration)
+		b.failures = 0
+	}
+}
+
+// Balancer picks which backend to send an attempt to.
+// It is given the healthy backends which haven't already failed during the Call, in their original order.
+type Balancer func(backends []*Backend) *Backend
+
+// RoundRobin sends requests to each backend in turn
+func RoundRobin() Balancer {
+	var next uint32
+	return func(backends []*Backend) *Backend {
+		n := atomic.AddUint32(&next, 1) - 1
+		return backends[int(n%uint32(len(backends)))]
+	}
+}
+
+// RandomChoice sends requests to a backend chosen at random
+func RandomChoice() Balancer {
+	return func(backends []*Backend) *Backend {
+		return backends[rand.Intn(len(backends))]
+	}
+}
+
+// LeastInFlight sends requests to the backend with the fewest requests in progress
+func LeastInFlight() Balancer {
+	return func(backends []*Backend) *Backend {
+		least := backends[0]
+		for _, backend := range backends[1:] {
+			if backend.InFlight() < least.InFlight() {
+				least = backend
+			}
+		}
+		return least
+	}
+}
+
+// PrimaryWithFailover sends requests to the first backend,
+// only using the others when it is failing or has been ejected
+func PrimaryWithFailover() Balancer {
+	return func(backends []*Backend) *Backend {
+		return backends[0]
+	}
+}
+
+type ejectionPolicy struct {
+	after    int
+	duration time.Duration
+}
+
+var defaultEjectionPolicy = ejectionPolicy{after: 5, duration: 30 * time.Second}
+
+// BaseURLs spreads requests across several base URLs, as chosen by the Balancer which defaults to RoundRobin.
+// When an attempt fails with a connection error or 5xx status, any retries go to a different backend.
+// Derived clients share the backends, including their health.
+func BaseURLs(bases ...string) Option {
+	backends := make([]*Backend, 0, len(bases))
+	for _, base := range bases {
+		u, err := url.Parse(base)
+		if err != nil {
+			return errOption(fmt.Errorf("invalid BaseURLs: %w", err))
+		}
+		backends = append(backends, &Backend{URL: u})
+	}
+	if len(backends) == 0 {
+		return errOption(errors.New("BaseURLs requires at least one URL"))
+	}
+	return func(c *Client) {
+		c.url = backends[0].URL
+		c.backends = backends
+		c.discovery = nil
+		if c.balancer == nil {
+			c.balancer = RoundRobin()
+		}
+	}
+}
+
+// Balance sets how requests are spread across the backends given to BaseURLs or found by Discover
+func Balance(balancer Balancer) Option {
+	return func(c *Client) {
+		c.balancer = balancer
+	}
+}
+
+// EjectBackends stops sending requests to a backend for d once it has failed n times in a row,
+// unless every backend has been ejected. The default is to eject for 30 seconds after 5 failures.
+// Setting n to 0 disables ejection.
+func EjectBackends(n int, d time.Duration) Option {
+	return func(c *Client) {
+		if !c.validCount("EjectBackends", n, 0) || !c.validDuration("EjectBackends", d) {
+			return
+		}
+		c.ejection = ejectionPolicy{after: n, duration: d}
+	}
+}
+
+// pickBackend chooses where the next attempt goes, avoiding backends which have already failed this Call.
+// Backends which are ejected or whose circuit is open are only used when there's nothing else left.
+func (c *Client) pickBackend(backends []*Backend, failed map[*Backend]bool) *Backend {
+	now := time.Now()
+	circuitOpen := func(backend *Backend) bool {
+		return c.breakers != nil && c.breakers.forURL(backend.URL).rejecting()
+	}
+	candidates := filterBackends(backends, func(backend *Backend) bool {
+		return !failed[backend] && !backend.ejected(now) && !circuitOpen(backend)
+	})
+	if len(candidates) == 0 {
+		candidates = filterBackends(backends, func(backend *Backend) bool {
+			return !failed[backend] && !circuitOpen(backend)
+		})
+	}
+	if len(candidates) == 0 {
+		candidates = filterBackends(backends, func(backend *Backend) bool {
+			return !failed[backend]
+		})
+	}
+	if len(candidates) == 0 {
+		candidates = backends
+	}
+	return c.balancer(candidates)
+}
+
+func filterBackends(backends []*Backend, keep func(backend *Backend) bool) []*Backend {
+	kept := make([]*Backend, 0, len(backends))
+	for _, backend := range backends {
+		if keep(backend) {
+			kept = append(kept, backend)
+		}
+	}
+	return kept
+}
+
+// balances reports whether attempts at target should be spread across backends,
+// which doesn't make sense for absolute URLs
+func balances(call callDetails) bool {
+	if len(call.backends) == 0 {
+		return false
+	}
+	targetURL, err := url.Parse(call.target)
+	return err == nil && !targetURL.IsAbs() && targetURL.Host == ""
+}
+
+// attemptBackend points the request at the next backend to try,
+// returning a function to record how the attempt went
+func (c *Client) attemptBackend(req *http.Request, call callDetails, failed map[*Backend]bool) (func(err error), error) {
+	backend := c.pickBackend(call.backends, failed)
+	u, err := buildURL(backend.URL, call.target, call.ums)
+	if err != nil {
+		return nil, err
+	}
+	req.URL = u
+	atomic.AddInt32(&backend.inFlight, 1)
+	return func(err error) {
+		atomic.AddInt32(&backend.inFlight, -1)
+		// neither cancelling nor being turned away by the circuit breaker says anything new about its health
+		if errors.Is(err, ErrCanceled) || errors.Is(err, ErrCircuitOpen) {
+			return
+		}
+		unhealthy := err != nil && DefaultIsFailure(err)
+		if unhealthy {
+			failed[backend] = true
+		}
+		backend.record(unhealthy, c.ejection, time.Now())
+	}, nil
+}
@@ -0,0 +1,174 @@
+package fourten_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestBaseURLs(t *testing.T) {
+	// backend starts a server which responds with status, counting the requests it receives
+	backend := func(t *testing.T, status int) (string, *int32) {
+		var requests int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(srv.Close)
+		return srv.URL, &requests
+	}
+	fastRetries := func(c *fourten.Client) {
+		fourten.RetryMaxAttempts(3)(c)
+		fourten.RetryBackoff(time.Millisecond, time.Millisecond, 1, 0)(c)
+	}
+
+	t.Run("round robin by default", func(t *testing.T) {
+		a, aRequests := backend(t, 200)
+		b, bRequests := backend(t, 200)
+		client := fourten.New(fourten.BaseURLs(a, b))
+
+		for i := 0; i < 4; i++ {
+			_, err := client.GET(ctx, "/ping", nil)
+			assert.NilError(t, err)
+		}
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(aRequests), int32(2)))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(bRequests), int32(2)))
+	})
+
+	t.Run("retries go to a different backend", func(t *testing.T) {
+		a, aRequests := backend(t, 503)
+		b, bRequests := backend(t, 200)
+		client := fourten.New(fourten.BaseURLs(a, b), fourten.Balance(fourten.PrimaryWithFailover()), fastRetries)
+
+		_, err := client.GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(aRequests), int32(1)))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(bRequests), int32(1)))
+	})
+
+	t.Run("connection failures fail over too", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		b, bRequests := backend(t, 200)
+		client := fourten.New(fourten.BaseURLs(closed.URL, b), fourten.Balance(fourten.PrimaryWithFailover()), fastRetries)
+
+		_, err := client.GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(bRequests), int32(1)))
+	})
+
+	t.Run("unhealthy backends are ejected for a while", func(t *testing.T) {
+		a, aRequests := backend(t, 500)
+		b, bRequests := backend(t, 200)
+		client := fourten.New(fourten.BaseURLs(a, b), fourten.Balance(fourten.PrimaryWithFailover()),
+			fourten.EjectBackends(2, 50*time.Millisecond))
+
+		for i := 0; i < 4; i++ {
+			_, _ = client.GET(ctx, "/ping", nil)
+		}
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(aRequests), int32(2)))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(bRequests), int32(2)))
+
+		time.Sleep(50 * time.Millisecond)
+		_, err := client.GET(ctx, "/ping", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrHTTP), "primary is back in use")
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(aRequests), int32(3)))
+	})
+
+	t.Run("backends whose circuit is open are passed over", func(t *testing.T) {
+		a, aRequests := backend(t, 500)
+		b, bRequests := backend(t, 200)
+		client := fourten.New(fourten.BaseURLs(a, b), fourten.EjectBackends(0, 0),
+			fourten.CircuitBreaker(fourten.CircuitBreakerSettings{MinRequests: 2, Window: time.Minute, OpenFor: time.Minute}))
+
+		for i := 0; i < 4; i++ {
+			_, _ = client.GET(ctx, "/ping", nil)
+		}
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(aRequests), int32(2)))
+		for i := 0; i < 4; i++ {
+			_, err := client.GET(ctx, "/ping", nil)
+			assert.NilError(t, err)
+		}
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(aRequests), int32(2)))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(bRequests), int32(6)))
+	})
+
+	t.Run("calls turned away by the circuit breaker aren't left in flight", func(t *testing.T) {
+		a, _ := backend(t, 500)
+		var picked *fourten.Backend
+		client := fourten.New(fourten.BaseURLs(a),
+			fourten.Balance(func(backends []*fourten.Backend) *fourten.Backend {
+				picked = backends[0]
+				return picked
+			}),
+			fourten.CircuitBreaker(fourten.CircuitBreakerSettings{MinRequests: 1, OpenFor: time.Minute}))
+
+		_, _ = client.GET(ctx, "/ping", nil)
+		_, err := client.GET(ctx, "/ping", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrCircuitOpen), "%v", err)
+		assert.Check(t, cmp.Equal(picked.InFlight(), 0))
+	})
+
+	t.Run("least in flight", func(t *testing.T) {
+		a, _ := backend(t, 200)
+		b, _ := backend(t, 200)
+		var seen []string
+		client := fourten.New(fourten.BaseURLs(a, b), fourten.Balance(fourten.LeastInFlight()),
+			fourten.Observe(func(req fourten.RequestInfo) fourten.ResponseObserver {
+				seen = append(seen, req.URL.Scheme+"://"+req.URL.Host)
+				return nil
+			}))
+
+		_, err := client.GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.DeepEqual(seen, []string{a}), "ties go to the first backend")
+	})
+
+	t.Run("absolute targets aren't balanced", func(t *testing.T) {
+		a, aRequests := backend(t, 200)
+		b, bRequests := backend(t, 200)
+		client := fourten.New(fourten.BaseURLs(a))
+
+		_, err := client.GET(ctx, b+"/ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(aRequests), int32(0)))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(bRequests), int32(1)))
+	})
+
+	t.Run("base paths and parameters are applied for each backend", func(t *testing.T) {
+		var paths []string
+		record := func(w http.ResponseWriter, r *http.Request) { paths = append(paths, r.URL.Path) }
+		a := httptest.NewServer(http.HandlerFunc(record))
+		defer a.Close()
+		b := httptest.NewServer(http.HandlerFunc(record))
+		defer b.Close()
+		client := fourten.New(fourten.BaseURLs(a.URL+"/v1/", b.URL+"/v2/"))
+
+		for i := 0; i < 2; i++ {
+			_, err := client.GET(ctx, "items/:id", nil, fourten.Param("id", "42"))
+			assert.NilError(t, err)
+		}
+		assert.Check(t, cmp.DeepEqual(paths, []string{"/v1/items/42", "/v2/items/42"}))
+	})
+
+	t.Run("a single BaseURL replaces them", func(t *testing.T) {
+		a, aRequests := backend(t, 200)
+		b, bRequests := backend(t, 200)
+		client := fourten.New(fourten.BaseURLs(a, b)).Derive(fourten.BaseURL(b))
+
+		for i := 0; i < 2; i++ {
+			_, err := client.GET(ctx, "/ping", nil)
+			assert.NilError(t, err)
+		}
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(aRequests), int32(0)))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(bRequests), int32(2)))
+	})
+}
@@ -0,0 +1,263 @@
+package fourten
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without making a request when a circuit breaker has tripped
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitBreakerSettings controls when a circuit breaker trips, and how it recovers
+type CircuitBreakerSettings struct {
+	// FailureRate is the fraction of failed requests within the Window which will open the circuit
+	FailureRate float64
+	// MinRequests prevents a handful of requests from opening the circuit, until there's enough to judge by
+	MinRequests int
+	// Window is the rolling period over which requests are counted
+	Window time.Duration
+	// OpenFor is how long the circuit stays open, failing fast, before letting trial requests through
+	OpenFor time.Duration
+	// HalfOpenRequests is the number of trial requests which must succeed for the circuit to close again
+	HalfOpenRequests int
+	// IsFailure decides which errors count against the upstream, defaulting to DefaultIsFailure
+	IsFailure func(err error) bool
+}
+
+var defaultCircuitBreakerSettings = CircuitBreakerSettings{
+	FailureRate:      0.5,
+	MinRequests:      20,
+	Window:           10 * time.Second,
+	OpenFor:          5 * time.Second,
+	HalfOpenRequests: 1,
+	IsFailure:        DefaultIsFailure,
+}
+
+// DefaultIsFailure counts transport errors and server errors as failures, as these suggest the upstream is unwell
+func DefaultIsFailure(err error) bool {
+	if httpErr := AsHTTPError(err); httpErr != nil {
+		return httpErr.Response.StatusCode >= 500
+	}
+	return AsTransportError(err) != nil
+}
+
+// CircuitBreaker stops sending requests to hosts which are failing, returning ErrCircuitOpen instead.
+// Each host gets its own breaker, which is shared with clients derived from this one.
+// With BaseURLs or Discover, backends whose circuit is open are passed over while there are others to use.
+// Any settings left as zero values use the defaults: opening at a 50% failure rate,
+// from at least 20 requests in 10 seconds, and then waiting 5 seconds before making a trial request.
+func CircuitBreaker(settings CircuitBreakerSettings) Option {
+	if settings.FailureRate <= 0 {
+		settings.FailureRate = defaultCircuitBreakerSettings.FailureRate
+	}
+	if settings.MinRequests <= 0 {
+		settings.MinRequests = defaultCircuitBreakerSettings.MinRequests
+	}
+	if settings.Window <= 0 {
+		settings.Window = defaultCircuitBreakerSettings.Window
+	}
+	if settings.OpenFor <= 0 {
+		settings.OpenFor = defaultCircuitBreakerSettings.OpenFor
+	}
+	if settings.HalfOpenRequests <= 0 {
+		settings.HalfOpenRequests = defaultCircuitBreakerSettings.HalfOpenRequests
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = defaultCircuitBreakerSettings.IsFailure
+	}
+	return func(c *Client) {
+		if settings.FailureRate > 1 {
+			c.invalid("CircuitBreaker FailureRate must be at most 1, got %v", settings.FailureRate)
+			return
+		}
+		c.breakers = &breakerGroup{
+			settings: settings,
+			breakers: make(map[string]*breaker),
+			now:      time.Now,
+		}
+	}
+}
+
+// DontBreakCircuit removes any circuit breaker from the client
+func DontBreakCircuit(c *Client) {
+	c.breakers = nil
+}
+
+// breakerGroup holds a breaker for each host a client talks to
+type breakerGroup struct {
+	settings CircuitBreakerSettings
+	now      func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+func (g *breakerGroup) forURL(u *url.URL) *breaker {
+	host := u.Scheme + "://" + u.Host
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	b, ok := g.breakers[host]
+	if !ok {
+		b = &breaker{
+			host:     host,
+			settings: &g.settings,
+			now:      g.now,
+			window:   newRollingWindow(g.settings.Window),
+		}
+		g.breakers[host] = b
+	}
+	return b
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type breaker struct {
+	host     string
+	settings *CircuitBreakerSettings
+	now      func() time.Time
+
+	mu        sync.Mutex
+	state     breakerState
+	openedAt  time.Time
+	window    *rollingWindow
+	trials    int
+	successes int
+}
+
+// allow checks whether a request can be made, returning a function to record how it went
+func (b *breaker) allow() (func(err error), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if b.state == breakerOpen && now.Sub(b.openedAt) >= b.settings.OpenFor {
+		b.state = breakerHalfOpen
+		b.trials = 0
+		b.successes = 0
+	}
+	switch b.state {
+	case breakerOpen:
+		return nil, fmt.Errorf("%s: %w", b.host, ErrCircuitOpen)
+	case breakerHalfOpen:
+		if b.trials >= b.settings.HalfOpenRequests {
+			return nil, fmt.Errorf("%s: %w", b.host, ErrCircuitOpen)
+		}
+		b.trials++
+	}
+	return b.record, nil
+}
+
+// rejecting reports whether allow would turn a request away right now, without taking a trial slot
+func (b *breaker) rejecting() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		return b.now().Sub(b.openedAt) < b.settings.OpenFor
+	case breakerHalfOpen:
+		return b.trials >= b.settings.HalfOpenRequests
+	}
+	return false
+}
+
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// cancellation says nothing about the upstream, so just frees up the trial slot if there was one
+	if errors.Is(err, ErrCanceled) {
+		if b.state == breakerHalfOpen {
+			b.trials--
+		}
+		return
+	}
+
+	now := b.now()
+	failed := err != nil && b.settings.IsFailure(err)
+	switch b.state {
+	case breakerClosed:
+		b.window.add(now, failed)
+		total, failures := b.window.counts(now)
+		if total >= b.settings.MinRequests && float64(failures)/float64(total) >= b.settings.FailureRate {
+			b.open(now)
+		}
+	case breakerHalfOpen:
+		if failed {
+			b.open(now)
+			return
+		}
+		if b.successes++; b.successes >= b.settings.HalfOpenRequests {
+			b.state = breakerClosed
+			b.window = newRollingWindow(b.settings.Window)
+		}
+	}
+}
+
+func (b *breaker) open(now time.Time) {
+	b.state = breakerOpen
+	b.openedAt = now
+}
+
+const rollingWindowBuckets = 10
+
+// rollingWindow counts outcomes in buckets, so that old ones can be dropped cheaply
+type rollingWindow struct {
+	width   time.Duration
+	buckets [rollingWindowBuckets]windowBucket
+}
+
+type windowBucket struct {
+	start    time.Time
+	total    int
+	failures int
+}
+
+func newRollingWindow(d time.Duration) *rollingWindow {
+	width := d / rollingWindowBuckets
+	if width <= 0 {
+		width = 1
+	}
+	return &rollingWindow{width: width}
+}
+
+func (w *rollingWindow) add(now time.Time, failed bool) {
+	start := now.Truncate(w.width)
+	b := &w.buckets[int(start.UnixNano()/int64(w.width))%rollingWindowBuckets]
+	if !b.start.Equal(start) {
+		*b = windowBucket{start: start}
+	}
+	b.total++
+	if failed {
+		b.failures++
+	}
+}
+
+func (w *rollingWindow) counts(now time.Time) (total, failures int) {
+	oldest := now.Truncate(w.width).Add(-w.width * (rollingWindowBuckets - 1))
+	for _, b := range w.buckets {
+		if !b.start.Before(oldest) {
+			total += b.total
+			failures += b.failures
+		}
+	}
+	return total, failures
+}
+
+// breakerFor finds the circuit breaker for a request, if the client has them
+func (c *Client) breakerFor(req *http.Request) *breaker {
+	if c.breakers == nil {
+		return nil
+	}
+	return c.breakers.forURL(req.URL)
+}
@@ -0,0 +1,105 @@
+package fourten_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	settings := fourten.CircuitBreakerSettings{
+		FailureRate: 0.5,
+		MinRequests: 4,
+		Window:      time.Minute,
+		OpenFor:     50 * time.Millisecond,
+	}
+
+	t.Run("opens once enough requests fail, and then fails fast", func(t *testing.T) {
+		requests := respondWithStatuses(t, 500)
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.CircuitBreaker(settings))
+
+		for i := 0; i < 4; i++ {
+			_, err := client.GET(ctx, "/down", nil)
+			assert.Check(t, errors.Is(err, fourten.ErrHTTP))
+		}
+		_, err := client.GET(ctx, "/down", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrCircuitOpen), "%v", err)
+		assert.Check(t, cmp.ErrorContains(err, server.URL))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(4)))
+	})
+
+	t.Run("client errors don't count as failures by default", func(t *testing.T) {
+		requests := respondWithStatuses(t, 404)
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.CircuitBreaker(settings))
+
+		for i := 0; i < 5; i++ {
+			_, err := client.GET(ctx, "/missing", nil)
+			assert.Check(t, !errors.Is(err, fourten.ErrCircuitOpen))
+		}
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(5)))
+	})
+
+	t.Run("failures can be classified differently", func(t *testing.T) {
+		respondWithStatuses(t, 404)
+		custom := settings
+		custom.IsFailure = func(err error) bool {
+			httpErr := fourten.AsHTTPError(err)
+			return httpErr != nil && httpErr.Response.StatusCode == 404
+		}
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.CircuitBreaker(custom))
+
+		for i := 0; i < 4; i++ {
+			_, _ = client.GET(ctx, "/missing", nil)
+		}
+		_, err := client.GET(ctx, "/missing", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrCircuitOpen), "%v", err)
+	})
+
+	t.Run("closes again after a successful trial request", func(t *testing.T) {
+		requests := respondWithStatuses(t, 500, 500, 500, 500, 200)
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.CircuitBreaker(settings))
+
+		for i := 0; i < 5; i++ {
+			_, _ = client.GET(ctx, "/recovering", nil)
+		}
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(4)))
+
+		time.Sleep(settings.OpenFor)
+		_, err := client.GET(ctx, "/recovering", nil)
+		assert.NilError(t, err)
+		_, err = client.GET(ctx, "/recovering", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(6)))
+	})
+
+	t.Run("derived clients share the breaker for the same host", func(t *testing.T) {
+		respondWithStatuses(t, 500)
+		parent := fourten.New(fourten.BaseURL(server.URL), fourten.CircuitBreaker(settings))
+		derived := parent.Derive(fourten.SetHeader("X-Derived", "yes"))
+
+		for i := 0; i < 4; i++ {
+			_, _ = parent.GET(ctx, "/down", nil)
+		}
+		_, err := derived.GET(ctx, "/down", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrCircuitOpen), "%v", err)
+
+		_, err = derived.Derive(fourten.DontBreakCircuit).GET(ctx, "/down", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrHTTP))
+	})
+
+	t.Run("stops retries once open", func(t *testing.T) {
+		requests := respondWithStatuses(t, 500)
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.CircuitBreaker(settings),
+			fourten.RetryMaxAttempts(10), fourten.RetryBackoff(time.Millisecond, time.Millisecond, 1, 0))
+
+		_, err := client.GET(ctx, "/down", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrCircuitOpen), "%v", err)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(4)))
+	})
+}
@@ -0,0 +1,103 @@
+package fourten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBulkheadFull is returned without making a request when too many Calls are already waiting for a slot
+var ErrBulkheadFull = errors.New("bulkhead full")
+
+// MaxConcurrent limits the number of Calls in flight at once, further Calls wait for a free slot.
+// A Call holds its slot across all retries, and a streamed Call holds its slot until the body is closed.
+// Derived clients share the same slots.
+func MaxConcurrent(n int) Option {
+	return maxConcurrent(n, -1)
+}
+
+// MaxConcurrentWithQueue is like MaxConcurrent, but once queue Calls are waiting for a slot
+// any more will fail immediately with ErrBulkheadFull
+func MaxConcurrentWithQueue(n, queue int) Option {
+	if queue < 0 {
+		return errOption(fmt.Errorf("MaxConcurrentWithQueue queue must be at least 0, got %d", queue))
+	}
+	return maxConcurrent(n, queue)
+}
+
+func maxConcurrent(n, queue int) Option {
+	return func(c *Client) {
+		if !c.validCount("MaxConcurrent", n, 1) {
+			return
+		}
+		c.bulkhead = &bulkhead{
+			slots:     make(chan struct{}, n),
+			maxQueued: int32(queue),
+		}
+	}
+}
+
+// DontLimitConcurrency removes any concurrency limit from the client
+func DontLimitConcurrency(c *Client) {
+	c.bulkhead = nil
+}
+
+type bulkhead struct {
+	slots     chan struct{}
+	maxQueued int32
+	queued    int32
+}
+
+func (b *bulkhead) acquire(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	queued := atomic.AddInt32(&b.queued, 1)
+	defer atomic.AddInt32(&b.queued, -1)
+	if b.maxQueued >= 0 && queued > b.maxQueued {
+		return fmt.Errorf("%w: %d calls in flight and %d waiting", ErrBulkheadFull, cap(b.slots), b.maxQueued)
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("stopped waiting for a free slot: %w", ctx.Err())
+	}
+}
+
+func (b *bulkhead) release() {
+	<-b.slots
+}
+
+func noopRelease() {}
+
+// enterBulkhead waits for a slot if the client has a concurrency limit,
+// returning a function to give the slot back and how long was spent waiting, whether or not it got one
+func (c *Client) enterBulkhead(ctx context.Context) (func(), time.Duration, error) {
+	if c.bulkhead == nil {
+		return noopRelease, 0, nil
+	}
+	start := time.Now()
+	if err := c.bulkhead.acquire(ctx); err != nil {
+		return nil, time.Since(start), err
+	}
+	// streamed bodies can be released more than once, the slot must only be given back once
+	var once sync.Once
+	b := c.bulkhead
+	return func() { once.Do(b.release) }, time.Since(start), nil
+}
+
+// inFlight reports how many Calls currently hold a slot, or 0 if there's no concurrency limit
+func (c *Client) inFlight() int {
+	if c.bulkhead == nil {
+		return 0
+	}
+	return len(c.bulkhead.slots)
+}
@@ -0,0 +1,130 @@
+package fourten_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestMaxConcurrent(t *testing.T) {
+	// blockingServer holds on to requests until unblocked, reporting the most it had in flight at once
+	blockingServer := func(t *testing.T) (string, chan struct{}, *int32) {
+		var current, most int32
+		unblock := make(chan struct{})
+		blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&current, 1)
+			defer atomic.AddInt32(&current, -1)
+			for {
+				m := atomic.LoadInt32(&most)
+				if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+					break
+				}
+			}
+			<-unblock
+		}))
+		t.Cleanup(blocking.Close)
+		return blocking.URL, unblock, &most
+	}
+
+	t.Run("limits the number of calls in flight", func(t *testing.T) {
+		url, unblock, most := blockingServer(t)
+		client := fourten.New(fourten.BaseURL(url), fourten.MaxConcurrent(2))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := client.GET(ctx, "/slow", nil)
+				assert.Check(t, err)
+			}()
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(unblock)
+		wg.Wait()
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(most), int32(2)))
+	})
+
+	t.Run("fails fast once the queue is full", func(t *testing.T) {
+		url, unblock, _ := blockingServer(t)
+		client := fourten.New(fourten.BaseURL(url), fourten.MaxConcurrentWithQueue(1, 1))
+		derived := client.Derive(fourten.SetHeader("X-Derived", "yes"))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := client.GET(ctx, "/slow", nil)
+				assert.Check(t, err)
+			}()
+		}
+		time.Sleep(50 * time.Millisecond)
+
+		_, err := derived.GET(ctx, "/slow", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrBulkheadFull), "%v", err)
+
+		close(unblock)
+		wg.Wait()
+	})
+
+	t.Run("stops waiting when the context is done", func(t *testing.T) {
+		url, unblock, _ := blockingServer(t)
+		defer close(unblock)
+		client := fourten.New(fourten.BaseURL(url), fourten.MaxConcurrent(1),
+			fourten.RequestTimeout(time.Second))
+
+		go func() { _, _ = client.GET(ctx, "/slow", nil) }()
+		time.Sleep(20 * time.Millisecond)
+
+		shortCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		_, err := client.GET(shortCtx, "/slow", nil)
+		assert.Check(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+		assert.Check(t, cmp.ErrorContains(err, "free slot"))
+	})
+
+	t.Run("streamed calls hold their slot until the body is closed", func(t *testing.T) {
+		var observed []fourten.RequestInfo
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.MaxConcurrentWithQueue(1, 0),
+			fourten.Observe(func(req fourten.RequestInfo) fourten.ResponseObserver {
+				observed = append(observed, req)
+				return nil
+			}))
+
+		res, err := client.Stream(ctx, "GET", "/stream", nil)
+		assert.NilError(t, err)
+		_, err = client.GET(ctx, "/ping", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrBulkheadFull), "%v", err)
+
+		assert.Check(t, res.Body.Close())
+		_, err = client.GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+
+		assert.Assert(t, cmp.Len(observed, 3))
+		assert.Check(t, cmp.Equal(observed[0].InFlight, 1))
+		assert.Check(t, cmp.Equal(observed[1].Attempt, 0), "the rejected call is observed")
+	})
+
+	t.Run("error bodies which were read up front don't hold on to their slot", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.MaxConcurrentWithQueue(1, 0),
+			fourten.DecodeJSON)
+
+		server.Response.Status = 500
+		_, err := client.Stream(ctx, "GET", "/stream", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrHTTP))
+
+		_, err = client.GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+	})
+}
@@ -0,0 +1,123 @@
+package fourten
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// CallOption changes how a single Call is made, without affecting the Client it is made with.
+// Every URLModifier is also a CallOption.
+type CallOption interface {
+	applyCall(s *callSettings)
+}
+
+// callSettings starts out as the Client's own settings, and is then adjusted by any CallOptions
+type callSettings struct {
+	ums     []URLModifier
+	header  http.Header
+	timeout time.Duration
+	retry   retryPolicy
+	decoder Decoder
+	expect  []int
+	err     error
+}
+
+func (c *Client) callSettings(opts []CallOption) callSettings {
+	s := callSettings{
+		timeout: c.timeout,
+		retry:   c.retry,
+		decoder: c.decoder,
+	}
+	for _, opt := range opts {
+		opt.applyCall(&s)
+	}
+	return s
+}
+
+type callOption func(s *callSettings)
+
+func (f callOption) applyCall(s *callSettings) {
+	f(s)
+}
+
+func (um URLModifier) applyCall(s *callSettings) {
+	s.ums = append(s.ums, um)
+}
+
+// CallHeader sets a header on a single Call, replacing any value set on the Client
+func CallHeader(header, value string) CallOption {
+	return callOption(func(s *callSettings) {
+		if s.header == nil {
+			s.header = make(http.Header)
+		}
+		s.header.Set(header, value)
+	})
+}
+
+// CallTimeout overrides the RequestTimeout for each attempt at a single Call
+func CallTimeout(d time.Duration) CallOption {
+	return callOption(func(s *callSettings) {
+		if d < 0 {
+			s.err = fmt.Errorf("CallTimeout must not be negative, got %v", d)
+			return
+		}
+		s.timeout = d
+	})
+}
+
+// CallMaxAttempts overrides RetryMaxAttempts for a single Call, any other retry settings are kept
+func CallMaxAttempts(n int) CallOption {
+	return callOption(func(s *callSettings) {
+		if n < 0 {
+			s.err = fmt.Errorf("CallMaxAttempts must be at least 0, got %d", n)
+			return
+		}
+		s.retry.maxAttempts = n
+	})
+}
+
+// CallDontRetry disables all retries for a single Call, including any custom strategy
+func CallDontRetry() CallOption {
+	return callOption(func(s *callSettings) {
+		s.retry = defaultRetryPolicy
+	})
+}
+
+// CallDecoder decodes the response to a single Call with decoder, sending accept as the Accept header like DecodeWith.
+// The decoder may be nil to leave the body to the caller, and an empty accept sends no Accept header at all.
+func CallDecoder(decoder Decoder, accept string) CallOption {
+	return callOption(func(s *callSettings) {
+		if s.header == nil {
+			s.header = make(http.Header)
+		}
+		if accept == "" {
+			// an empty list removes the Client's header when they're merged
+			s.header["Accept"] = []string{}
+		} else {
+			s.header.Set("Accept", accept)
+		}
+		s.decoder = decoder
+	})
+}
+
+// ExpectStatus treats only the listed status codes as success for a single Call.
+// Any other status, including a 2xx which isn't listed, produces an HTTPError.
+func ExpectStatus(codes ...int) CallOption {
+	return callOption(func(s *callSettings) {
+		s.expect = codes
+	})
+}
+
+// failed decides whether a response status should be turned into an HTTPError
+func (s callSettings) failed(status int) bool {
+	if s.expect == nil {
+		return status >= 300
+	}
+	for _, code := range s.expect {
+		if status == code {
+			return false
+		}
+	}
+	return true
+}
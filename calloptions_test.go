@@ -0,0 +1,147 @@
+package fourten_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestCallOptions(t *testing.T) {
+	t.Run("headers only apply to the one call", func(t *testing.T) {
+		var headers []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headers = append(headers, r.Header.Get("X-Trace"))
+		}))
+		defer srv.Close()
+		client := fourten.New(fourten.BaseURL(srv.URL), fourten.SetHeader("X-Trace", "client"))
+
+		_, err := client.GET(ctx, "/", nil, fourten.CallHeader("X-Trace", "call"))
+		assert.Check(t, err)
+		_, err = client.GET(ctx, "/", nil)
+		assert.Check(t, err)
+		assert.Check(t, cmp.DeepEqual(headers, []string{"call", "client"}))
+	})
+
+	t.Run("can be mixed with URL modifiers", func(t *testing.T) {
+		var path string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path + " " + r.Header.Get("X-Trace")
+		}))
+		defer srv.Close()
+		client := fourten.New(fourten.BaseURL(srv.URL))
+
+		_, err := client.POST(ctx, "/items/:id", nil, nil, fourten.Param("id", "123"), fourten.CallHeader("X-Trace", "abc"))
+		assert.Check(t, err)
+		assert.Check(t, cmp.Equal(path, "/items/123 abc"))
+	})
+
+	t.Run("timeout overrides the request timeout", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+		}))
+		defer srv.Close()
+		client := fourten.New(fourten.BaseURL(srv.URL), fourten.RequestTimeout(time.Second))
+
+		_, err := client.GET(ctx, "/", nil, fourten.CallTimeout(10*time.Millisecond))
+		assert.Check(t, errors.Is(err, fourten.ErrRequestTimeout))
+		_, err = client.GET(ctx, "/", nil)
+		assert.Check(t, err)
+	})
+
+	t.Run("invalid options fail the call", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL))
+		_, err := client.GET(ctx, "/ping", nil, fourten.CallTimeout(-time.Second))
+		assert.Check(t, cmp.ErrorContains(err, "CallTimeout must not be negative"))
+	})
+
+	t.Run("retries can be changed for one call", func(t *testing.T) {
+		var attempts int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+		client := fourten.New(
+			fourten.BaseURL(srv.URL),
+			fourten.RetryMaxAttempts(3),
+			fourten.RetryBackoff(time.Millisecond, time.Millisecond, 1, 0),
+		)
+
+		_, err := client.GET(ctx, "/", nil, fourten.CallDontRetry())
+		assert.Check(t, errors.Is(err, fourten.ErrHTTP))
+		assert.Check(t, cmp.Equal(atomic.SwapInt32(&attempts, 0), int32(1)))
+
+		_, err = client.GET(ctx, "/", nil, fourten.CallMaxAttempts(2))
+		assert.Check(t, errors.Is(err, fourten.ErrHTTP))
+		assert.Check(t, cmp.Equal(atomic.SwapInt32(&attempts, 0), int32(2)))
+
+		_, err = client.GET(ctx, "/", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrHTTP))
+		assert.Check(t, cmp.Equal(atomic.SwapInt32(&attempts, 0), int32(3)))
+	})
+
+	t.Run("decoder can be replaced or removed", func(t *testing.T) {
+		var accept []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accept = r.Header.Values("Accept")
+			_, _ = w.Write([]byte("plain text"))
+		}))
+		defer srv.Close()
+		client := fourten.New(fourten.BaseURL(srv.URL), fourten.DecodeJSON)
+
+		text := func(contentType string, r io.Reader, target interface{}) error {
+			b, err := ioutil.ReadAll(r)
+			*target.(*string) = string(b)
+			return err
+		}
+		var out string
+		_, err := client.GET(ctx, "/", &out, fourten.CallDecoder(text, "text/plain"))
+		assert.Check(t, err)
+		assert.Check(t, cmp.Equal(out, "plain text"))
+		assert.Check(t, cmp.DeepEqual(accept, []string{"text/plain"}))
+
+		res, err := client.GET(ctx, "/", nil, fourten.CallDecoder(nil, ""))
+		assert.NilError(t, err)
+		defer res.Body.Close()
+		b, err := ioutil.ReadAll(res.Body)
+		assert.Check(t, err)
+		assert.Check(t, cmp.Equal(string(b), "plain text"))
+		assert.Check(t, cmp.Len(accept, 0))
+
+		_, err = client.GET(ctx, "/", &out, fourten.CallDecoder(nil, ""))
+		assert.Check(t, cmp.ErrorContains(err, "no decoder configured"))
+
+		_, err = client.GET(ctx, "/", &map[string]string{})
+		assert.Check(t, cmp.ErrorContains(err, "JSON"))
+		assert.Check(t, cmp.DeepEqual(accept, []string{"application/json"}), "the client's header is untouched")
+	})
+
+	t.Run("expected statuses decide what counts as an error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer srv.Close()
+		client := fourten.New(fourten.BaseURL(srv.URL), fourten.DecodeJSON)
+
+		res, err := client.GET(ctx, "/missing", nil, fourten.ExpectStatus(http.StatusOK, http.StatusNotFound))
+		assert.Check(t, err)
+		assert.Check(t, cmp.Equal(res.StatusCode, http.StatusNotFound))
+
+		_, err = client.GET(ctx, "/", nil, fourten.ExpectStatus(http.StatusCreated))
+		httpErr := fourten.AsHTTPError(err)
+		assert.Assert(t, httpErr != nil)
+		assert.Check(t, cmp.Equal(httpErr.Response.StatusCode, http.StatusOK))
+	})
+}
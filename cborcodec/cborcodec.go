@@ -0,0 +1,63 @@
+// Package cborcodec encodes and decodes fourten requests and responses as CBOR.
+// It lives in its own module so that the CBOR dependency is only needed by clients which use it.
+package cborcodec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/glenjamin/fourten"
+)
+
+// ContentType is the content type sent with CBOR request bodies
+const ContentType = "application/cbor"
+
+// Encode sends request bodies as CBOR
+func Encode(c *fourten.Client) {
+	fourten.EncodeWith(Encoder)(c)
+}
+
+// Decode decodes responses as CBOR
+func Decode(c *fourten.Client) {
+	fourten.DecodeWith(Decoder, ContentType)(c)
+}
+
+// Encoder is the fourten.Encoder used by Encode
+func Encoder(input interface{}) (fourten.RequestEncoding, error) {
+	b, err := cbor.Marshal(input)
+	if err != nil {
+		return fourten.RequestEncoding{}, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", ContentType)
+	return fourten.RequestEncoding{
+		ContentLength: int64(len(b)),
+		GetBody: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		},
+		Header: header,
+	}, nil
+}
+
+// Decoder is the fourten.Decoder used by Decode
+func Decoder(contentType string, r io.Reader, target interface{}) error {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != ContentType {
+		return errors.New("expected CBOR content-type, got " + contentType)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read: %w", err)
+	}
+	if err := cbor.Unmarshal(b, target); err != nil {
+		return fmt.Errorf("failed to decode: %w", err)
+	}
+	return nil
+}
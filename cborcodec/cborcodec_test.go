@@ -0,0 +1,67 @@
+package cborcodec_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+	"github.com/glenjamin/fourten/cborcodec"
+)
+
+var ctx = context.Background()
+
+type reading struct {
+	Sensor string
+	Values []float64
+}
+
+func TestCodec(t *testing.T) {
+	// echo sends the request body straight back, recording the headers it was sent
+	var contentType, accept string
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType, accept = r.Header.Get("Content-Type"), r.Header.Get("Accept")
+		if r.URL.Path == "/text" {
+			w.Header().Set("Content-Type", "text/plain")
+		} else {
+			w.Header().Set("Content-Type", contentType)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		_, _ = w.Write(b)
+	}))
+	defer echo.Close()
+	client := fourten.New(fourten.BaseURL(echo.URL), cborcodec.Encode, cborcodec.Decode)
+	input := reading{Sensor: "temp", Values: []float64{20.5, 21}}
+
+	t.Run("round trips", func(t *testing.T) {
+		var out reading
+		_, err := client.POST(ctx, "/echo", input, &out)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.DeepEqual(out, input))
+		assert.Check(t, cmp.Equal(contentType, "application/cbor"))
+		assert.Check(t, cmp.Equal(accept, "application/cbor"))
+	})
+
+	t.Run("encodes in the expected format", func(t *testing.T) {
+		res, err := client.Derive(fourten.DontDecode).POST(ctx, "/echo", input, nil)
+		assert.NilError(t, err)
+		defer res.Body.Close()
+		b, err := ioutil.ReadAll(res.Body)
+		assert.NilError(t, err)
+		var out reading
+		assert.NilError(t, cbor.Unmarshal(b, &out))
+		assert.Check(t, cmp.DeepEqual(out, input))
+	})
+
+	t.Run("rejects other content types", func(t *testing.T) {
+		var out reading
+		_, err := client.POST(ctx, "/text", input, &out)
+		assert.Check(t, cmp.ErrorContains(err, "expected CBOR content-type, got text/plain"))
+	})
+}
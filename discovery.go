@@ -0,0 +1,232 @@
+package fourten
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolver finds the base URLs for a logical service name
+type Resolver interface {
+	Resolve(ctx context.Context, service string) ([]*url.URL, error)
+}
+
+// ResolverFunc allows a plain function to be used as a Resolver
+type ResolverFunc func(ctx context.Context, service string) ([]*url.URL, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context, service string) ([]*url.URL, error) {
+	return f(ctx, service)
+}
+
+// Discover uses resolver to find the base URLs for service, which are then used as with BaseURLs.
+// Results are cached for refresh, after which they are resolved again in the background while Calls
+// carry on using the old results. Only the first Call has to wait, as there is nothing to use until then.
+// If resolving fails after previously succeeding, the old results continue to be used until the next refresh.
+// Each resolution has its own 10 second timeout, and derived clients share the same cache.
+func Discover(service string, resolver Resolver, refresh time.Duration) Option {
+	return func(c *Client) {
+		if resolver == nil {
+			c.invalid("Discover needs a Resolver")
+			return
+		}
+		if !c.validDuration("Discover refresh", refresh) {
+			return
+		}
+		c.discovery = &discovery{
+			service:  service,
+			resolver: resolver,
+			refresh:  refresh,
+		}
+		c.backends = nil
+		if c.balancer == nil {
+			c.balancer = RoundRobin()
+		}
+	}
+}
+
+// resolveTimeout limits how long a Resolver has to find a service, as resolving isn't tied to any one Call
+const resolveTimeout = 10 * time.Second
+
+type discovery struct {
+	service  string
+	resolver Resolver
+	refresh  time.Duration
+
+	mu       sync.Mutex
+	backends []*Backend
+	fetched  time.Time
+	resolve  *resolution
+}
+
+// resolution is a single lookup of the service, which Calls without any backends yet wait for
+type resolution struct {
+	done chan struct{}
+	err  error
+}
+
+// currentBackends finds the backends to use for a Call, which is nil when there's just the one BaseURL
+func (c *Client) currentBackends(ctx context.Context) ([]*Backend, error) {
+	if c.discovery == nil {
+		return c.backends, nil
+	}
+	return c.discovery.current(ctx)
+}
+
+func (d *discovery) current(ctx context.Context) ([]*Backend, error) {
+	d.mu.Lock()
+	backends := d.backends
+	if backends != nil && time.Since(d.fetched) < d.refresh {
+		d.mu.Unlock()
+		return backends, nil
+	}
+	r := d.resolve
+	if r == nil {
+		r = &resolution{done: make(chan struct{})}
+		d.resolve = r
+		go d.update(r)
+	}
+	d.mu.Unlock()
+
+	// stale results are served while they're being refreshed
+	if backends != nil {
+		return backends, nil
+	}
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", d.service, r.err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.backends, nil
+}
+
+// update resolves the service and stores the results, without holding the lock while the Resolver runs
+func (d *discovery) update(r *resolution) {
+	defer close(r.done)
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	urls, err := d.resolver.Resolve(ctx, d.service)
+	cancel()
+	if err == nil && len(urls) == 0 {
+		err = errors.New("no backends found")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.resolve = nil
+	if err != nil {
+		r.err = err
+		if d.backends != nil {
+			// stale results are better than none, but don't try again on every Call
+			d.fetched = time.Now()
+		}
+		return
+	}
+
+	// backends which are still around keep their health
+	previous := make(map[string]*Backend, len(d.backends))
+	for _, backend := range d.backends {
+		previous[backend.URL.String()] = backend
+	}
+	backends := make([]*Backend, 0, len(urls))
+	for _, u := range urls {
+		if backend, ok := previous[u.String()]; ok {
+			backends = append(backends, backend)
+		} else {
+			backends = append(backends, &Backend{URL: u})
+		}
+	}
+	d.backends = backends
+	d.fetched = time.Now()
+}
+
+// DNSSRVResolver looks up services via DNS SRV records, such as "_http._tcp.example.com".
+// Targets are ordered by priority and weight, and turned into URLs using scheme.
+func DNSSRVResolver(scheme string) Resolver {
+	return ResolverFunc(func(ctx context.Context, service string) ([]*url.URL, error) {
+		_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", service)
+		if err != nil {
+			return nil, err
+		}
+		return srvURLs(scheme, records), nil
+	})
+}
+
+// srvURLs turns SRV records into base URLs, keeping the order they were sorted into by the lookup
+func srvURLs(scheme string, records []*net.SRV) []*url.URL {
+	urls := make([]*url.URL, 0, len(records))
+	for _, srv := range records {
+		host := strings.TrimSuffix(srv.Target, ".")
+		urls = append(urls, &url.URL{
+			Scheme: scheme,
+			Host:   net.JoinHostPort(host, strconv.Itoa(int(srv.Port))),
+		})
+	}
+	return urls
+}
+
+// FileResolver reads services from a JSON file mapping each service name to a list of base URLs, e.g.
+//
+//	{"users": ["http://10.0.0.1:8080", "http://10.0.0.2:8080"]}
+//
+// The file isn't watched, instead it is checked whenever Discover refreshes, and only read again if it has been modified.
+// Changes are therefore picked up within the refresh interval given to Discover.
+func FileResolver(path string) Resolver {
+	return &fileResolver{path: path}
+}
+
+type fileResolver struct {
+	path string
+
+	mu       sync.Mutex
+	modified time.Time
+	services map[string][]string
+}
+
+func (r *fileResolver) Resolve(_ context.Context, service string) ([]*url.URL, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return nil, err
+	}
+	if r.services == nil || !info.ModTime().Equal(r.modified) {
+		b, err := ioutil.ReadFile(r.path)
+		if err != nil {
+			return nil, err
+		}
+		var services map[string][]string
+		if err := json.Unmarshal(b, &services); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", r.path, err)
+		}
+		r.services = services
+		r.modified = info.ModTime()
+	}
+
+	bases, ok := r.services[service]
+	if !ok {
+		return nil, fmt.Errorf("service %s not found in %s", service, r.path)
+	}
+	urls := make([]*url.URL, 0, len(bases))
+	for _, base := range bases {
+		u, err := url.Parse(base)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
@@ -0,0 +1,192 @@
+package fourten_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/poll"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestDiscovery(t *testing.T) {
+	// fakeResolver hands out whatever URLs it currently has, counting how often it's asked
+	type fakeResolver struct {
+		urls    atomic.Value
+		lookups int32
+	}
+	newFakeResolver := func(bases ...string) *fakeResolver {
+		f := &fakeResolver{}
+		f.urls.Store(bases)
+		return f
+	}
+	resolve := func(f *fakeResolver) fourten.Resolver {
+		return fourten.ResolverFunc(func(_ context.Context, service string) ([]*url.URL, error) {
+			atomic.AddInt32(&f.lookups, 1)
+			if service != "pinger" {
+				return nil, fmt.Errorf("unknown service %s", service)
+			}
+			var urls []*url.URL
+			for _, base := range f.urls.Load().([]string) {
+				u, _ := url.Parse(base)
+				urls = append(urls, u)
+			}
+			return urls, nil
+		})
+	}
+
+	t.Run("requests go to the resolved URLs", func(t *testing.T) {
+		fake := newFakeResolver(server.URL + "/discovered/")
+		client := fourten.New(fourten.Discover("pinger", resolve(fake), time.Minute))
+
+		_, err := client.GET(ctx, "ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(server.Request.URL.Path, "/discovered/ping"))
+	})
+
+	t.Run("results are cached until the refresh interval", func(t *testing.T) {
+		fake := newFakeResolver(server.URL + "/first/")
+		client := fourten.New(fourten.Discover("pinger", resolve(fake), 50*time.Millisecond))
+
+		_, err := client.GET(ctx, "ping", nil)
+		assert.NilError(t, err)
+		fake.urls.Store([]string{server.URL + "/second/"})
+		_, err = client.Derive().GET(ctx, "ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(server.Request.URL.Path, "/first/ping"))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(&fake.lookups), int32(1)))
+
+		time.Sleep(50 * time.Millisecond)
+		_, err = client.GET(ctx, "ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(server.Request.URL.Path, "/first/ping"))
+		poll.WaitOn(t, func(poll.LogT) poll.Result {
+			if atomic.LoadInt32(&fake.lookups) < 2 {
+				return poll.Continue("waiting for refresh")
+			}
+			return poll.Success()
+		})
+		_, err = client.GET(ctx, "ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(server.Request.URL.Path, "/second/ping"))
+	})
+
+	t.Run("calls use stale results while a slow refresh runs", func(t *testing.T) {
+		var lookups int32
+		unblock := make(chan struct{})
+		t.Cleanup(func() { close(unblock) })
+		resolver := fourten.ResolverFunc(func(_ context.Context, service string) ([]*url.URL, error) {
+			if atomic.AddInt32(&lookups, 1) > 1 {
+				<-unblock
+			}
+			u, _ := url.Parse(server.URL + "/stale/")
+			return []*url.URL{u}, nil
+		})
+		client := fourten.New(fourten.Discover("pinger", resolver, time.Nanosecond))
+
+		for i := 0; i < 3; i++ {
+			callCtx, cancel := context.WithTimeout(ctx, time.Second)
+			_, err := client.GET(callCtx, "ping", nil)
+			cancel()
+			assert.NilError(t, err)
+		}
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(&lookups), int32(2)))
+	})
+
+	t.Run("resolving isn't cancelled along with the call which started it", func(t *testing.T) {
+		var lookups int32
+		resolver := fourten.ResolverFunc(func(resolveCtx context.Context, service string) ([]*url.URL, error) {
+			atomic.AddInt32(&lookups, 1)
+			select {
+			case <-time.After(50 * time.Millisecond):
+			case <-resolveCtx.Done():
+				return nil, resolveCtx.Err()
+			}
+			u, _ := url.Parse(server.URL + "/resolved/")
+			return []*url.URL{u}, nil
+		})
+		client := fourten.New(fourten.Discover("pinger", resolver, time.Minute))
+
+		impatient, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err := client.GET(impatient, "ping", nil)
+		assert.Check(t, errors.Is(err, context.DeadlineExceeded), err)
+
+		_, err = client.GET(ctx, "ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(server.Request.URL.Path, "/resolved/ping"))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(&lookups), int32(1)))
+	})
+
+	t.Run("resolution failures are errors", func(t *testing.T) {
+		fake := newFakeResolver()
+		client := fourten.New(fourten.Discover("pinger", resolve(fake), time.Minute))
+		_, err := client.GET(ctx, "ping", nil)
+		assert.Check(t, cmp.ErrorContains(err, "failed to resolve pinger: no backends found"))
+
+		client = fourten.New(fourten.Discover("unknown", resolve(fake), time.Minute))
+		_, err = client.GET(ctx, "ping", nil)
+		assert.Check(t, cmp.ErrorContains(err, "unknown service"))
+	})
+
+	t.Run("stale results are used if resolving fails", func(t *testing.T) {
+		var fail int32
+		resolver := fourten.ResolverFunc(func(_ context.Context, service string) ([]*url.URL, error) {
+			if atomic.LoadInt32(&fail) == 1 {
+				return nil, errors.New("resolver down")
+			}
+			u, _ := url.Parse(server.URL + "/stale/")
+			return []*url.URL{u}, nil
+		})
+		client := fourten.New(fourten.Discover("pinger", resolver, time.Nanosecond))
+
+		_, err := client.GET(ctx, "ping", nil)
+		assert.NilError(t, err)
+		atomic.StoreInt32(&fail, 1)
+		_, err = client.GET(ctx, "ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(server.Request.URL.Path, "/stale/ping"))
+	})
+
+	t.Run("file resolver reads services from JSON, noticing changes", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "fourten")
+		assert.NilError(t, err)
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		path := filepath.Join(dir, "services.json")
+		write := func(base string, modified time.Time) {
+			content := fmt.Sprintf(`{"pinger": [%q]}`, base)
+			assert.NilError(t, ioutil.WriteFile(path, []byte(content), 0600))
+			assert.NilError(t, os.Chtimes(path, modified, modified))
+		}
+		write(server.URL+"/from-file/", time.Now().Add(-time.Hour))
+		client := fourten.New(fourten.Discover("pinger", fourten.FileResolver(path), time.Nanosecond))
+
+		_, err = client.GET(ctx, "ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(server.Request.URL.Path, "/from-file/ping"))
+
+		write(server.URL+"/changed/", time.Now())
+		poll.WaitOn(t, func(poll.LogT) poll.Result {
+			if _, err := client.GET(ctx, "ping", nil); err != nil {
+				return poll.Error(err)
+			}
+			if server.Request.URL.Path != "/changed/ping" {
+				return poll.Continue("still using %s", server.Request.URL.Path)
+			}
+			return poll.Success()
+		})
+
+		_, err = fourten.FileResolver(path).Resolve(ctx, "unknown")
+		assert.Check(t, cmp.ErrorContains(err, "not found"))
+	})
+}
@@ -0,0 +1,98 @@
+package fourten
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// These errors classify why a request failed without receiving a response, and can be checked with errors.Is
+var (
+	ErrTimeout           = errors.New("timeout")
+	ErrCanceled          = errors.New("canceled")
+	ErrConnectionRefused = errors.New("connection refused")
+	ErrConnectionReset   = errors.New("connection reset")
+	ErrDNS               = errors.New("DNS lookup failed")
+	ErrTLS               = errors.New("TLS failure")
+)
+
+// TransportError is returned when a request fails without receiving a response.
+// Alongside errors.Is(err, fourten.ErrTimeout) and friends, Kind can be used directly in a switch.
+type TransportError struct {
+	Method string
+	URL    *url.URL
+	// Kind is one of the classification errors above, or nil if we don't recognise the failure
+	Kind error
+	Err  error
+}
+
+func (e *TransportError) Error() string {
+	return e.Err.Error()
+}
+
+// Is allows TransportError to match errors.Is against its Kind
+func (e *TransportError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+func AsTransportError(err error) *TransportError {
+	var transportErr *TransportError
+	if errors.As(err, &transportErr) {
+		return transportErr
+	}
+	return nil
+}
+
+// classifyTransport wraps up the error from a request which never got a response.
+// Anything which didn't come from the http.Client, such as failing to produce the request body, is left alone.
+func classifyTransport(req *http.Request, err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	return &TransportError{
+		Method: req.Method,
+		URL:    req.URL,
+		Kind:   transportErrorKind(err),
+		Err:    err,
+	}
+}
+
+func transportErrorKind(err error) error {
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certificateErr x509.CertificateInvalidError
+	var recordHeaderErr tls.RecordHeaderError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrCanceled
+	// timeouts come first, as a TLS handshake or DNS lookup can also time out
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrTimeout
+	case errors.As(err, &dnsErr):
+		return ErrDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return ErrConnectionReset
+	case errors.As(err, &unknownAuthorityErr), errors.As(err, &hostnameErr),
+		errors.As(err, &certificateErr), errors.As(err, &recordHeaderErr):
+		return ErrTLS
+	// crypto/tls reports most handshake failures as plain errors, so the message is all we have to go on
+	case strings.Contains(err.Error(), "tls: "):
+		return ErrTLS
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package fourten_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestTransportErrors(t *testing.T) {
+	t.Run("connection refused", func(t *testing.T) {
+		// Grab a free port, and then stop listening on it
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NilError(t, err)
+		addr := listener.Addr().String()
+		listener.Close()
+
+		client := fourten.New(fourten.BaseURL("http://" + addr))
+		_, err = client.GET(ctx, "/nobody-home", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrConnectionRefused), "%v", err)
+
+		transportErr := fourten.AsTransportError(err)
+		assert.Assert(t, transportErr != nil)
+		assert.Check(t, cmp.Equal(transportErr.Kind, fourten.ErrConnectionRefused))
+		assert.Check(t, cmp.Equal(transportErr.Method, "GET"))
+		assert.Check(t, cmp.Equal(transportErr.URL.String(), "http://"+addr+"/nobody-home"))
+		var urlErr *url.Error
+		assert.Check(t, errors.As(err, &urlErr), "original error is still available")
+	})
+
+	t.Run("DNS failure", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL("http://fourten.invalid"))
+		_, err := client.GET(ctx, "/anywhere", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrDNS), "%v", err)
+	})
+
+	t.Run("TLS failure", func(t *testing.T) {
+		secure := httptest.NewTLSServer(http.NotFoundHandler())
+		defer secure.Close()
+
+		// The test server's certificate isn't trusted by default
+		client := fourten.New(fourten.BaseURL(secure.URL))
+		_, err := client.GET(ctx, "/untrusted", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrTLS), "%v", err)
+	})
+
+	t.Run("timeouts", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RequestTimeout(time.Nanosecond))
+		server.Delay = time.Millisecond
+
+		_, err := client.GET(ctx, "/slow", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrTimeout), "%v", err)
+		assert.Check(t, errors.Is(err, fourten.ErrRequestTimeout), "%v", err)
+		assert.Check(t, fourten.AsTransportError(err) != nil)
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		client := fourten.New(fourten.BaseURL(server.URL))
+		_, err := client.GET(canceled, "/cancelled", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrCanceled), "%v", err)
+		assert.Check(t, !errors.Is(err, fourten.ErrTimeout))
+	})
+
+	t.Run("status errors are not transport errors", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL))
+		server.Response.Status = 500
+
+		_, err := client.GET(ctx, "/error", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrHTTP))
+		assert.Check(t, fourten.AsTransportError(err) == nil)
+	})
+}
@@ -0,0 +1,202 @@
+package fourten
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EncodeForm sends request bodies as application/x-www-form-urlencoded.
+// Input can be url.Values, map[string]string, or a struct whose fields are named by `form:"..."` tags.
+// Tags follow the same conventions as encoding/json, so `form:"-"` skips a field and `form:"name,omitempty"`
+// leaves it out when empty. Nested structs and maps become name[field], slices of plain values repeat the name,
+// and slices of structs become name[0][field].
+func EncodeForm(c *Client) {
+	c.setEncoder("EncodeForm", formEncoder)
+}
+
+func formEncoder(input interface{}) (RequestEncoding, error) {
+	values, err := formValues(input)
+	if err != nil {
+		return RequestEncoding{}, err
+	}
+	b := []byte(values.Encode())
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return RequestEncoding{
+		ContentLength: int64(len(b)),
+		GetBody: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		},
+		Header: header,
+	}, nil
+}
+
+func formValues(input interface{}) (url.Values, error) {
+	switch v := input.(type) {
+	case url.Values:
+		return v, nil
+	case map[string][]string:
+		return v, nil
+	case map[string]string:
+		values := make(url.Values, len(v))
+		for k, s := range v {
+			values.Set(k, s)
+		}
+		return values, nil
+	}
+
+	rv := reflect.ValueOf(input)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot form encode %T, expected url.Values, map[string]string or a struct", input)
+	}
+	values := make(url.Values)
+	if err := encodeFormStruct(values, "", rv); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// encodeFormValue adds v to values under key, recursing into structs, maps and slices
+func encodeFormValue(values url.Values, key string, v reflect.Value) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Type().Implements(textMarshalerType) && v.CanInterface() {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return fmt.Errorf("failed to form encode %s: %w", key, err)
+		}
+		values.Add(key, string(text))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		values.Add(key, v.String())
+	case reflect.Bool:
+		values.Add(key, strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		values.Add(key, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		values.Add(key, strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		values.Add(key, strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()))
+	case reflect.Struct:
+		return encodeFormStruct(values, key, v)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("cannot form encode %s, map keys must be strings", key)
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := encodeFormValue(values, key+"["+iter.Key().String()+"]", iter.Value()); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			elemKey := key
+			if isFormNested(v.Index(i)) {
+				elemKey = key + "[" + strconv.Itoa(i) + "]"
+			}
+			if err := encodeFormValue(values, elemKey, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot form encode %s of type %s", key, v.Type())
+	}
+	return nil
+}
+
+// encodeFormStruct adds each field of v, nested inside prefix unless it's the top level
+func encodeFormStruct(values url.Values, prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		name, omitEmpty := parseFormTag(field)
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+		// embedded structs without a name of their own are flattened into the parent
+		if field.Anonymous && field.Tag.Get("form") == "" {
+			for fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if err := encodeFormStruct(values, prefix, fv); err != nil {
+					return err
+				}
+				continue
+			}
+			if field.PkgPath != "" {
+				continue
+			}
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "[" + name + "]"
+		}
+		if err := encodeFormValue(values, key, fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseFormTag(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("form")
+	name, opts := tag, ""
+	if i := strings.Index(tag, ","); i >= 0 {
+		name, opts = tag[:i], tag[i+1:]
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(","+opts+",", ",omitempty,")
+}
+
+// isFormNested reports whether v will be encoded as several keys, which need an index to keep them together
+func isFormNested(v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	if v.Type().Implements(textMarshalerType) {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package fourten_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestEncodeForm(t *testing.T) {
+	var contentType, body string
+	forms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		contentType, body = r.Header.Get("Content-Type"), string(b)
+	}))
+	defer forms.Close()
+	client := fourten.New(fourten.BaseURL(forms.URL), fourten.EncodeForm)
+
+	decoded := func(t *testing.T) url.Values {
+		values, err := url.ParseQuery(body)
+		assert.NilError(t, err)
+		return values
+	}
+
+	t.Run("url.Values", func(t *testing.T) {
+		_, err := client.POST(ctx, "/token", url.Values{"grant_type": {"client_credentials"}, "scope": {"a", "b"}}, nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(contentType, "application/x-www-form-urlencoded"))
+		assert.Check(t, cmp.Equal(body, "grant_type=client_credentials&scope=a&scope=b"))
+	})
+
+	t.Run("map[string]string", func(t *testing.T) {
+		_, err := client.POST(ctx, "/token", map[string]string{"a": "1 2", "b": "&"}, nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(body, "a=1+2&b=%26"))
+	})
+
+	t.Run("structs", func(t *testing.T) {
+		type address struct {
+			Street string `form:"street"`
+			City   string `form:"city"`
+		}
+		type Common struct {
+			Source string `form:"source"`
+		}
+		type line struct {
+			SKU string `form:"sku"`
+			Qty int    `form:"qty"`
+		}
+		type order struct {
+			Common
+			Name     string    `form:"name"`
+			Note     string    `form:"note,omitempty"`
+			Secret   string    `form:"-"`
+			Paid     bool      `form:"paid"`
+			Total    float64   `form:"total"`
+			Tags     []string  `form:"tags"`
+			Address  *address  `form:"address"`
+			Billing  *address  `form:"billing"`
+			Lines    []line    `form:"lines"`
+			Placed   time.Time `form:"placed"`
+			Untagged int
+			private  string
+		}
+		input := order{
+			Common:   Common{Source: "web"},
+			Name:     "Bob",
+			Secret:   "shh",
+			Paid:     true,
+			Total:    12.5,
+			Tags:     []string{"x", "y"},
+			Address:  &address{Street: "1 High St", City: "Leeds"},
+			Lines:    []line{{SKU: "A1", Qty: 2}, {SKU: "B2", Qty: 1}},
+			Placed:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			Untagged: 7,
+			private:  "hidden",
+		}
+		_, err := client.POST(ctx, "/orders", &input, nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.DeepEqual(decoded(t), url.Values{
+			"source":          {"web"},
+			"name":            {"Bob"},
+			"paid":            {"true"},
+			"total":           {"12.5"},
+			"tags":            {"x", "y"},
+			"address[street]": {"1 High St"},
+			"address[city]":   {"Leeds"},
+			"lines[0][sku]":   {"A1"},
+			"lines[0][qty]":   {"2"},
+			"lines[1][sku]":   {"B2"},
+			"lines[1][qty]":   {"1"},
+			"placed":          {"2020-01-02T03:04:05Z"},
+			"Untagged":        {"7"},
+		}))
+	})
+
+	t.Run("bodies can be replayed for retries", func(t *testing.T) {
+		var bodies []string
+		flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(b))
+			if len(bodies) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer flaky.Close()
+		retrying := fourten.New(
+			fourten.BaseURL(flaky.URL),
+			fourten.EncodeForm,
+			fourten.RetryMaxAttempts(2),
+			fourten.RetryBackoff(time.Millisecond, time.Millisecond, 1, 0),
+		)
+		_, err := retrying.PUT(ctx, "/", url.Values{"a": {"b"}}, nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.DeepEqual(bodies, []string{"a=b", "a=b"}))
+	})
+
+	t.Run("unsupported inputs", func(t *testing.T) {
+		_, err := client.POST(ctx, "/", []string{"nope"}, nil)
+		assert.Check(t, cmp.ErrorContains(err, "cannot form encode []string"))
+
+		_, err = client.POST(ctx, "/", struct{ F func() }{}, nil)
+		assert.Check(t, cmp.ErrorContains(err, "cannot form encode F"))
+	})
+}
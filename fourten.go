@@ -20,11 +20,33 @@ type Client struct {
 	url     *url.URL
 	headers http.Header
 
-	timeout time.Duration
-	encoder Encoder
-	decoder Decoder
-
-	httpClient *http.Client
+	timeout           time.Duration
+	totalTimeout      time.Duration
+	streamIdleTimeout time.Duration
+	encoder           Encoder
+	decoder           Decoder
+	retry             retryPolicy
+	idempotencyKeys   func() string
+	breakers          *breakerGroup
+	rateLimit         *tokenBucket
+	routeRateLimit    *routeRateLimit
+	adaptiveRateLimit *adaptiveRateLimit
+	bulkhead          *bulkhead
+	hedge             hedgePolicy
+	retryBudget       *retryBudget
+	backends          []*Backend
+	balancer          Balancer
+	ejection          ejectionPolicy
+	discovery         *discovery
+
+	// options is only set while options are being applied
+	options *optionState
+
+	observers []Observer
+
+	httpClient      *http.Client
+	transport       *http.Transport
+	sharedTransport bool
 }
 
 // Option is used to apply changes to a Client in a neat manner
@@ -48,52 +70,95 @@ type Decoder func(contentType string, r io.Reader, target interface{}) error
 
 const defaultUserAgent = "fourten (Go HTTP Client)"
 
-// New constructs a Client, applying the specified options
+// New constructs a Client, applying the specified options.
+// It panics if any of the options are invalid, use NewE to get an error instead.
 func New(opts ...Option) *Client {
+	c, err := NewE(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func newClient() *Client {
+	transport := newTransport()
 	c := &Client{
 		url:        &url.URL{},
 		headers:    make(http.Header),
 		timeout:    time.Second,
-		httpClient: &http.Client{},
+		retry:      defaultRetryPolicy,
+		httpClient: &http.Client{Transport: transport},
+		transport:  transport,
+
+		streamIdleTimeout: defaultStreamIdleTimeout,
+		ejection:          defaultEjectionPolicy,
 	}
 	c.headers.Set("User-Agent", defaultUserAgent)
-	for _, opt := range opts {
-		opt(c)
-	}
 	return c
 }
 
-// Derive copies the current client, applying additional options as specified
+// Derive copies the current client, applying additional options as specified.
+// It panics if any of the options are invalid, use DeriveE to get an error instead.
 func (c *Client) Derive(opts ...Option) *Client {
+	derived, err := c.DeriveE(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return derived
+}
+
+func (c *Client) derive() *Client {
 	httpClient := *c.httpClient
 
 	derived := &Client{
-		url:        c.url.ResolveReference(&url.URL{}),
-		headers:    c.headers.Clone(),
-		timeout:    c.timeout,
-		encoder:    c.encoder,
-		decoder:    c.decoder,
-		httpClient: &httpClient,
-	}
-	for _, opt := range opts {
-		opt(derived)
+		url:          c.url.ResolveReference(&url.URL{}),
+		headers:      c.headers.Clone(),
+		timeout:      c.timeout,
+		totalTimeout: c.totalTimeout,
+		encoder:      c.encoder,
+		decoder:      c.decoder,
+		retry:        c.retry,
+		observers:    c.observers,
+		httpClient:   &httpClient,
+		transport:    c.transport,
+
+		sharedTransport: true,
+
+		streamIdleTimeout: c.streamIdleTimeout,
+		idempotencyKeys:   c.idempotencyKeys,
+		breakers:          c.breakers,
+		rateLimit:         c.rateLimit,
+		routeRateLimit:    c.routeRateLimit,
+		adaptiveRateLimit: c.adaptiveRateLimit,
+		bulkhead:          c.bulkhead,
+		hedge:             c.hedge,
+		retryBudget:       c.retryBudget,
+		backends:          c.backends,
+		balancer:          c.balancer,
+		ejection:          c.ejection,
+		discovery:         c.discovery,
 	}
 	return derived
 }
 
+// RequestTimeout limits the time taken by each attempt at a request, including reading the response body
 func RequestTimeout(d time.Duration) Option {
 	return func(c *Client) {
-		c.timeout = d
+		if c.validDuration("RequestTimeout", d) {
+			c.timeout = d
+		}
 	}
 }
 
 func BaseURL(base string) Option {
 	u, err := url.Parse(base)
 	if err != nil {
-		panic(err)
+		return errOption(fmt.Errorf("invalid BaseURL: %w", err))
 	}
 	return func(c *Client) {
 		c.url = u
+		c.backends = nil
+		c.discovery = nil
 	}
 }
 
@@ -161,7 +226,7 @@ func NoFollow(c *Client) {
 }
 
 func EncodeJSON(c *Client) {
-	c.encoder = jsonEncoder
+	c.setEncoder("EncodeJSON", jsonEncoder)
 }
 func jsonEncoder(input interface{}) (RequestEncoding, error) {
 	// A little sleight of hand to ensure we only encode once, regardless of how many readers are needed
@@ -195,6 +260,21 @@ func jsonDecoder(contentType string, r io.Reader, target interface{}) error {
 	return nil
 }
 
+// EncodeWith sends request bodies using a custom encoder, such as one for a format fourten doesn't support itself
+func EncodeWith(encoder Encoder) Option {
+	return func(c *Client) {
+		c.setEncoder("EncodeWith", encoder)
+	}
+}
+
+// DecodeWith decodes responses using a custom decoder, sending accept as the Accept header
+func DecodeWith(decoder Decoder, accept string) Option {
+	return func(c *Client) {
+		SetHeader("Accept", accept)(c)
+		c.decoder = decoder
+	}
+}
+
 func DontDecode(c *Client) {
 	c.headers.Del("Accept")
 	c.decoder = nil
@@ -202,6 +282,10 @@ func DontDecode(c *Client) {
 
 func GzipRequests(c *Client) {
 	encoder := c.encoder
+	if encoder == nil {
+		c.invalid("%w", errNoEncoder)
+		return
+	}
 	c.encoder = func(input interface{}) (RequestEncoding, error) {
 		enc, err := encoder(input)
 		if err != nil {
@@ -215,6 +299,7 @@ func GzipRequests(c *Client) {
 		if err != nil {
 			return RequestEncoding{}, err
 		}
+		defer r.Close()
 		var buf bytes.Buffer
 		gzw := gzip.NewWriter(&buf)
 		if _, err = io.Copy(gzw, r); err != nil {
@@ -234,69 +319,250 @@ func GzipRequests(c *Client) {
 }
 
 // GET makes an HTTP request to the supplied target.
-// It is the responsibility of the caller to close the response body if output is nil
-func (c *Client) GET(ctx context.Context, target string, output interface{}, ums ...URLModifier) (*http.Response, error) {
-	return c.Call(ctx, "GET", target, nil, output, ums...)
+// It is the responsibility of the caller to close the response body if output is nil.
+// Any CallOptions, including URLModifiers, only apply to this Call.
+func (c *Client) GET(ctx context.Context, target string, output interface{}, opts ...CallOption) (*http.Response, error) {
+	return c.Call(ctx, "GET", target, nil, output, opts...)
 }
-func (c *Client) HEAD(ctx context.Context, target string, ums ...URLModifier) (*http.Response, error) {
-	return c.Call(ctx, "HEAD", target, nil, nil, ums...)
+func (c *Client) HEAD(ctx context.Context, target string, opts ...CallOption) (*http.Response, error) {
+	return c.Call(ctx, "HEAD", target, nil, nil, opts...)
 }
-func (c *Client) OPTIONS(ctx context.Context, target string, output interface{}, ums ...URLModifier) (*http.Response, error) {
-	return c.Call(ctx, "OPTIONS", target, nil, output, ums...)
+func (c *Client) OPTIONS(ctx context.Context, target string, output interface{}, opts ...CallOption) (*http.Response, error) {
+	return c.Call(ctx, "OPTIONS", target, nil, output, opts...)
 }
-func (c *Client) POST(ctx context.Context, target string, input, output interface{}, ums ...URLModifier) (*http.Response, error) {
-	return c.Call(ctx, "POST", target, input, output, ums...)
+func (c *Client) POST(ctx context.Context, target string, input, output interface{}, opts ...CallOption) (*http.Response, error) {
+	return c.Call(ctx, "POST", target, input, output, opts...)
 }
-func (c *Client) PUT(ctx context.Context, target string, input, output interface{}, ums ...URLModifier) (*http.Response, error) {
-	return c.Call(ctx, "PUT", target, input, output, ums...)
+func (c *Client) PUT(ctx context.Context, target string, input, output interface{}, opts ...CallOption) (*http.Response, error) {
+	return c.Call(ctx, "PUT", target, input, output, opts...)
 }
-func (c *Client) PATCH(ctx context.Context, target string, input, output interface{}, ums ...URLModifier) (*http.Response, error) {
-	return c.Call(ctx, "PATCH", target, input, output, ums...)
+func (c *Client) PATCH(ctx context.Context, target string, input, output interface{}, opts ...CallOption) (*http.Response, error) {
+	return c.Call(ctx, "PATCH", target, input, output, opts...)
 }
-func (c *Client) DELETE(ctx context.Context, target string, input, output interface{}, ums ...URLModifier) (*http.Response, error) {
-	return c.Call(ctx, "DELETE", target, input, output, ums...)
+func (c *Client) DELETE(ctx context.Context, target string, input, output interface{}, opts ...CallOption) (*http.Response, error) {
+	return c.Call(ctx, "DELETE", target, input, output, opts...)
 }
 
-func (c *Client) Call(ctx context.Context, method, target string, input, output interface{}, ums ...URLModifier) (*http.Response, error) {
-	if output != nil && c.decoder == nil {
+func (c *Client) Call(ctx context.Context, method, target string, input, output interface{}, opts ...CallOption) (*http.Response, error) {
+	settings := c.callSettings(opts)
+	if output != nil && settings.decoder == nil {
 		return nil, errors.New("output requested but no decoder configured")
 	}
+	return c.call(ctx, method, target, input, output, false, settings)
+}
+
+// Stream makes an HTTP request, handing back the response body without decoding it.
+// The RequestTimeout only applies until the response headers arrive, after which reading the body
+// is limited by the StreamIdleTimeout instead.
+// It is the responsibility of the caller to close the response body, which releases all resources.
+func (c *Client) Stream(ctx context.Context, method, target string, input interface{}, opts ...CallOption) (*http.Response, error) {
+	return c.call(ctx, method, target, input, nil, true, c.callSettings(opts))
+}
 
-	req, err := c.buildRequest(method, target, ums)
+func (c *Client) call(ctx context.Context, method, target string, input, output interface{}, stream bool, settings callSettings) (*http.Response, error) {
+	if settings.err != nil {
+		return nil, settings.err
+	}
+
+	backends, err := c.currentBackends(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
-	defer cancel()
-	req = req.WithContext(ctx)
+	req, err := c.buildRequest(method, target, backends, settings.ums)
+	if err != nil {
+		return nil, err
+	}
+	copyHeaders(req.Header, settings.header)
 
 	err = c.setupEncoding(req, input)
 	if err != nil {
 		return nil, err
 	}
+	c.setupIdempotencyKey(req)
 
-	res, err := c.httpClient.Do(req)
+	release, queued, err := c.enterBulkhead(ctx)
 	if err != nil {
+		c.observeRejection(req, target, queued, queued, err)
 		return nil, err
 	}
 
-	httpErr := coerceHTTPError(res)
+	details := callDetails{
+		callSettings: settings,
+		target:       target,
+		backends:     backends,
+		output:       output,
+		stream:       stream,
+		queued:       queued,
+	}
+	if c.totalTimeout <= 0 {
+		res, err := c.attempts(ctx, req, details)
+		releaseAfter(res, release)
+		return res, err
+	}
+
+	totalCtx, cancel := context.WithTimeout(ctx, c.totalTimeout)
+	res, err := c.attempts(totalCtx, req, details)
+	if err != nil && ctx.Err() == nil && totalCtx.Err() == context.DeadlineExceeded {
+		err = &timeoutError{kind: ErrTotalTimeout, err: err}
+	}
+	releaseAfter(res, func() {
+		cancel()
+		release()
+	})
+	return res, err
+}
+
+// releaseAfter calls release once the Call is finished with.
+// A streamed body is still using the Call's resources, so can only release them once closed.
+func releaseAfter(res *http.Response, release func()) {
+	if body, ok := streamBodyOf(res); ok {
+		body.alsoCancel(release)
+	} else {
+		release()
+	}
+}
+
+// callDetails holds what each attempt needs to know about the Call, beyond the request itself
+type callDetails struct {
+	callSettings
+	target   string
+	backends []*Backend
+	output   interface{}
+	stream   bool
+	// queued is the time spent waiting for a bulkhead slot
+	queued time.Duration
+}
+
+// attempts makes the request, retrying as many times as the retry policy allows
+func (c *Client) attempts(ctx context.Context, req *http.Request, call callDetails) (*http.Response, error) {
+	var retrier Retrier
+	if isRetryable(req) {
+		retrier = call.retry.retrier()
+	}
+	var failedBackends map[*Backend]bool
+	if balances(call) {
+		failedBackends = make(map[*Backend]bool)
+	}
+
+	start := time.Now()
+	if c.retryBudget != nil {
+		c.retryBudget.deposit(start)
+	}
+	for attempt := 1; ; attempt++ {
+		if err := c.waitForRateLimit(ctx, call.target); err != nil {
+			return nil, err
+		}
+		var recordBackend func(err error)
+		if failedBackends != nil {
+			var err error
+			if recordBackend, err = c.attemptBackend(req, call, failedBackends); err != nil {
+				return nil, err
+			}
+		}
+		var record func(err error)
+		if breaker := c.breakerFor(req); breaker != nil {
+			var err error
+			if record, err = breaker.allow(); err != nil {
+				c.observeRejection(req, call.target, call.queued, time.Since(start), err)
+				if recordBackend != nil {
+					recordBackend(err)
+				}
+				return nil, err
+			}
+		}
+		observed := c.observe(RequestInfo{
+			Method:   req.Method,
+			Target:   call.target,
+			URL:      req.URL,
+			Attempt:  attempt,
+			Queued:   call.queued,
+			InFlight: c.inFlight(),
+		})
+		attemptStart := time.Now()
+		var res *http.Response
+		var err error
+		if call.stream {
+			res, err = c.streamAttempt(ctx, req, call)
+		} else {
+			res, err = c.attempt(ctx, req, call)
+		}
+		info := ResponseInfo{
+			Response: res,
+			Err:      err,
+			Attempt:  attempt,
+			Duration: time.Since(attemptStart),
+			Elapsed:  time.Since(start),
+		}
+		if res != nil {
+			info.StatusCode = res.StatusCode
+			if quota, ok := parseQuota(res.Header, time.Now()); ok {
+				info.Quota = &quota
+				if c.adaptiveRateLimit != nil {
+					c.adaptiveRateLimit.update(quota)
+				}
+			}
+		}
+		observed(info)
+		if record != nil {
+			record(err)
+		}
+		if recordBackend != nil {
+			recordBackend(err)
+		}
+
+		if err == nil {
+			return res, nil
+		}
+		// the response is only returned alongside an error if the error describes it
+		if AsHTTPError(err) == nil {
+			res = nil
+		}
+		delay := c.retryDelay(ctx, retrier, call.retry, start, err)
+		if delay < 0 {
+			return res, err
+		}
+		if c.retryBudget != nil && !c.retryBudget.withdraw(time.Now()) {
+			return res, &retryBudgetError{err: err}
+		}
+		// the body may have been left for the caller, but they'll never see this one
+		if res != nil && (call.stream || call.decoder == nil) {
+			_, _ = io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, fmt.Errorf("stopped waiting to retry: %w", err)
+		}
+	}
+}
+
+// attempt makes a single request, decoding the response if we're responsible for doing so.
+// The response is returned whenever one was received, even if decoding it then failed.
+func (c *Client) attempt(ctx context.Context, req *http.Request, call callDetails) (*http.Response, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, call.timeout)
+	defer cancel()
+
+	res, err := c.hedgedSend(attemptCtx, req, call.target)
+	if err != nil {
+		return nil, classifyTransport(req, classifyTimeout(ctx, attemptCtx.Err() != nil, err))
+	}
+
+	httpErr := coerceHTTPError(res, call.failed)
 
 	// non-nil decoder means we are responsible for output decoding
-	if c.decoder != nil {
+	if call.decoder != nil {
 		// when we handle output, we close body - otherwise it's up to the caller
 		defer res.Body.Close()
 
 		// if we have an http error don't decode to output, it's unlikely to match
 		// instead, we'll read from res to free the connection up, but store the data for later use
 		if httpErr != nil {
-			if err := httpErr.populateBody(c.decoder); err != nil {
-				return nil, fmt.Errorf("failed to read error body: %w", err)
+			if err := httpErr.populateBody(call.decoder); err != nil {
+				return res, fmt.Errorf("failed to read error body: %w", err)
 			}
 		} else {
-			if err := handleDecoding(res, c.decoder, output); err != nil {
-				return nil, err
+			if err := handleDecoding(res, call.decoder, call.output); err != nil {
+				return res, err
 			}
 		}
 	}
@@ -307,25 +573,51 @@ func (c *Client) Call(ctx context.Context, method, target string, input, output
 	return res, nil
 }
 
-func (c *Client) buildRequest(method, target string, ums []URLModifier) (*http.Request, error) {
-	targetURL, err := url.Parse(target)
+// send makes a single request using a fresh copy of the request body
+func (c *Client) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	req = req.WithContext(ctx)
+
+	var err error
+	if req.Body, err = req.GetBody(); err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+func (c *Client) buildRequest(method, target string, backends []*Backend, ums []URLModifier) (*http.Request, error) {
+	base := c.url
+	if len(backends) > 0 {
+		base = backends[0].URL
+	}
+	u, err := buildURL(base, target, ums)
 	if err != nil {
 		return nil, err
 	}
 
 	req := &http.Request{
 		Method: method,
-		URL:    c.url.ResolveReference(targetURL),
+		URL:    u,
 		Header: c.headers.Clone(),
 	}
 
+	return req, nil
+}
+
+func buildURL(base *url.URL, target string, ums []URLModifier) (*url.URL, error) {
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+
+	u := base.ResolveReference(targetURL)
 	for _, um := range ums {
-		if err := um(req.URL); err != nil {
+		if err := um(u); err != nil {
 			return nil, err
 		}
 	}
 
-	return req, nil
+	return u, nil
 }
 
 func (c *Client) setupEncoding(req *http.Request, input interface{}) error {
@@ -341,20 +633,20 @@ func (c *Client) setupEncoding(req *http.Request, input interface{}) error {
 		req.ContentLength = encoding.ContentLength
 		req.GetBody = encoding.GetBody
 		copyHeaders(req.Header, encoding.Header)
-		if req.Body, err = encoding.GetBody(); err != nil {
-			return fmt.Errorf("failed to read body from encoding of %v: %w", input, err)
-		}
 	} else {
 		req.ContentLength = 0
 		req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
 	}
-	var err error
-	req.Body, err = req.GetBody()
-	return err
+	return nil
 }
 
+// copyHeaders replaces headers in base with those from merge, where an empty list of values removes the header
 func copyHeaders(base http.Header, merge http.Header) {
 	for header, values := range merge {
+		if len(values) == 0 {
+			delete(base, header)
+			continue
+		}
 		base[header] = values
 	}
 }
@@ -377,9 +669,12 @@ func handleDecoding(res *http.Response, decoder Decoder, output interface{}) err
 	return decoder(res.Header.Get("content-type"), res.Body, output)
 }
 
-func coerceHTTPError(res *http.Response) *HTTPError {
-	if res.StatusCode >= 300 {
-		return &HTTPError{Response: res}
+func coerceHTTPError(res *http.Response, failed func(status int) bool) *HTTPError {
+	if failed(res.StatusCode) {
+		httpErr := &HTTPError{Response: res}
+		httpErr.retryAfter, httpErr.hasRetryAfter = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+		httpErr.quota, httpErr.hasQuota = parseQuota(res.Header, time.Now())
+		return httpErr
 	}
 	return nil
 }
@@ -399,6 +694,11 @@ type HTTPError struct {
 
 	body    *bytes.Buffer
 	decoder Decoder
+
+	retryAfter    time.Duration
+	hasRetryAfter bool
+	quota         Quota
+	hasQuota      bool
 }
 
 func (e *HTTPError) populateBody(decoder Decoder) error {
@@ -428,3 +728,14 @@ func (e *HTTPError) Decode(output interface{}) error {
 func (e *HTTPError) Body() string {
 	return e.body.String()
 }
+
+// RetryAfter reports how long the server asked us to wait before trying again, via the Retry-After header.
+// Dates are converted into a delay relative to when the response was received.
+func (e *HTTPError) RetryAfter() (time.Duration, bool) {
+	return e.retryAfter, e.hasRetryAfter
+}
+
+// Quota reports the rate limit quota the server included with the response, if any
+func (e *HTTPError) Quota() (Quota, bool) {
+	return e.quota, e.hasQuota
+}
@@ -14,6 +14,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -64,7 +65,7 @@ func TestURLResolution(t *testing.T) {
 	})
 
 	t.Run("panics on invalid base URL", func(t *testing.T) {
-		// panic because fitting an error into the Option signature would be a pain
+		// New panics, use NewE to get the error instead
 		assert.Assert(t, cmp.Panics(func() {
 			fourten.New(fourten.BaseURL(":/:/:"))
 		}))
@@ -415,6 +416,40 @@ func TestEncodingAndDecoding(t *testing.T) {
 
 		assert.Check(t, cmp.DeepEqual(output, map[string]interface{}{"easy_as": 123.0}))
 	})
+
+	t.Run("Can plug in custom encoders and decoders", func(t *testing.T) {
+		upper := func(input interface{}) (fourten.RequestEncoding, error) {
+			b := []byte(strings.ToUpper(input.(string)))
+			return fourten.RequestEncoding{
+				ContentLength: int64(len(b)),
+				GetBody: func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader(b)), nil
+				},
+				Header: http.Header{"Content-Type": {"text/shouting"}},
+			}, nil
+		}
+		lower := func(contentType string, r io.Reader, target interface{}) error {
+			b, err := ioutil.ReadAll(r)
+			*target.(*string) = contentType + " " + strings.ToLower(string(b))
+			return err
+		}
+		client := fourten.New(fourten.BaseURL(server.URL),
+			fourten.EncodeWith(upper), fourten.DecodeWith(lower, "text/whispering"))
+
+		server.Response.Headers = Headers{"content-type": []string{"text/plain"}}
+		server.Response.Body = "QUIET"
+
+		var output string
+		_, err := client.POST(ctx, "/data", "loud", &output)
+		assert.NilError(t, err)
+
+		requestBody, err := ioutil.ReadAll(server.Request.Body)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(string(requestBody), "LOUD"))
+		assert.Check(t, cmp.Equal(server.Request.Header.Get("Content-Type"), "text/shouting"))
+		assert.Check(t, cmp.Equal(server.Request.Header.Get("Accept"), "text/whispering"))
+		assert.Check(t, cmp.Equal(output, "text/plain quiet"))
+	})
 }
 
 func TestMethods(t *testing.T) {
@@ -432,7 +467,7 @@ func TestMethods(t *testing.T) {
 	t.Run("without bodies", func(t *testing.T) {
 		tests := []struct {
 			method string
-			fn     func(context.Context, string, interface{}, ...fourten.URLModifier) (*http.Response, error)
+			fn     func(context.Context, string, interface{}, ...fourten.CallOption) (*http.Response, error)
 		}{
 			{"GET", client.GET},
 			{"OPTIONS", client.OPTIONS},
@@ -452,7 +487,7 @@ func TestMethods(t *testing.T) {
 	t.Run("without bodies but with decoding", func(t *testing.T) {
 		tests := []struct {
 			method string
-			fn     func(context.Context, string, interface{}, ...fourten.URLModifier) (*http.Response, error)
+			fn     func(context.Context, string, interface{}, ...fourten.CallOption) (*http.Response, error)
 		}{
 			{"GET", client.GET},
 			{"OPTIONS", client.OPTIONS},
@@ -477,14 +512,14 @@ func TestMethods(t *testing.T) {
 	t.Run("with bodies", func(t *testing.T) {
 		tests := []struct {
 			method string
-			fn     func(context.Context, string, interface{}, interface{}, ...fourten.URLModifier) (*http.Response, error)
+			fn     func(context.Context, string, interface{}, interface{}, ...fourten.CallOption) (*http.Response, error)
 		}{
 			{"POST", client.POST},
 			{"PUT", client.PUT},
 			{"PATCH", client.PATCH},
 			{"DELETE", client.DELETE},
-			{"ANYTHING", func(ctx context.Context, s string, i, o interface{}, ums ...fourten.URLModifier) (*http.Response, error) {
-				return client.Call(ctx, "ANYTHING", s, i, nil, ums...)
+			{"ANYTHING", func(ctx context.Context, s string, i, o interface{}, opts ...fourten.CallOption) (*http.Response, error) {
+				return client.Call(ctx, "ANYTHING", s, i, nil, opts...)
 			}},
 		}
 		for _, test := range tests {
@@ -509,14 +544,14 @@ func TestMethods(t *testing.T) {
 	t.Run("with bodies and decoding", func(t *testing.T) {
 		tests := []struct {
 			method string
-			fn     func(context.Context, string, interface{}, interface{}, ...fourten.URLModifier) (*http.Response, error)
+			fn     func(context.Context, string, interface{}, interface{}, ...fourten.CallOption) (*http.Response, error)
 		}{
 			{"POST", client.POST},
 			{"PUT", client.PUT},
 			{"PATCH", client.PATCH},
 			{"DELETE", client.DELETE},
-			{"ANYTHING", func(ctx context.Context, s string, i, o interface{}, ums ...fourten.URLModifier) (*http.Response, error) {
-				return client.Call(ctx, "ANYTHING", s, i, o, ums...)
+			{"ANYTHING", func(ctx context.Context, s string, i, o interface{}, opts ...fourten.CallOption) (*http.Response, error) {
+				return client.Call(ctx, "ANYTHING", s, i, o, opts...)
 			}},
 		}
 		for _, test := range tests {
@@ -0,0 +1,64 @@
+package fourten
+
+import (
+	"context"
+	"net/http"
+)
+
+// Empty can be used as the input of an Endpoint which sends no body, or the output of one which decodes nothing
+type Empty struct{}
+
+// Get makes a GET request to target, decoding the response into a new T
+func Get[T any](ctx context.Context, c *Client, target string, opts ...CallOption) (T, *http.Response, error) {
+	return Call[Empty, T](ctx, c, "GET", target, Empty{}, opts...)
+}
+
+// Post makes a POST request to target, encoding input and decoding the response into a new Out
+func Post[In, Out any](ctx context.Context, c *Client, target string, input In, opts ...CallOption) (Out, *http.Response, error) {
+	return Call[In, Out](ctx, c, "POST", target, input, opts...)
+}
+
+// Put makes a PUT request to target, encoding input and decoding the response into a new Out
+func Put[In, Out any](ctx context.Context, c *Client, target string, input In, opts ...CallOption) (Out, *http.Response, error) {
+	return Call[In, Out](ctx, c, "PUT", target, input, opts...)
+}
+
+// Patch makes a PATCH request to target, encoding input and decoding the response into a new Out
+func Patch[In, Out any](ctx context.Context, c *Client, target string, input In, opts ...CallOption) (Out, *http.Response, error) {
+	return Call[In, Out](ctx, c, "PATCH", target, input, opts...)
+}
+
+// Delete makes a DELETE request to target, decoding the response into a new Out
+func Delete[Out any](ctx context.Context, c *Client, target string, opts ...CallOption) (Out, *http.Response, error) {
+	return Call[Empty, Out](ctx, c, "DELETE", target, Empty{}, opts...)
+}
+
+// Call is the typed equivalent of Client.Call.
+// An Empty input sends no body, and an Empty output leaves the response body to the caller as with a nil output.
+func Call[In, Out any](ctx context.Context, c *Client, method, target string, input In, opts ...CallOption) (Out, *http.Response, error) {
+	var out Out
+	var body, output interface{} = input, &out
+	if _, ok := body.(Empty); ok {
+		body = nil
+	}
+	if _, ok := output.(*Empty); ok {
+		output = nil
+	}
+	res, err := c.Call(ctx, method, target, body, output, opts...)
+	return out, res, err
+}
+
+// Endpoint describes an API operation once, so that it can be called with the right types wherever it's needed, e.g.
+//
+//	var GetUser = fourten.Endpoint[fourten.Empty, User]{Method: "GET", Path: "/users/:id"}
+//	user, _, err := GetUser.Call(ctx, client, fourten.Empty{}, fourten.Param("id", "123"))
+type Endpoint[In, Out any] struct {
+	Method string
+	// Path is the target of the request, which may contain parameters to fill in with URLModifiers
+	Path string
+}
+
+// Call makes a request to the endpoint using c
+func (e Endpoint[In, Out]) Call(ctx context.Context, c *Client, input In, opts ...CallOption) (Out, *http.Response, error) {
+	return Call[In, Out](ctx, c, e.Method, e.Path, input, opts...)
+}
@@ -0,0 +1,74 @@
+package fourten_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestGenericHelpers(t *testing.T) {
+	var lastBody string
+	users := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		lastBody = string(b)
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(user{ID: r.URL.Path[len("/users/"):], Name: r.Method})
+	}))
+	defer users.Close()
+	client := fourten.New(fourten.BaseURL(users.URL), fourten.EncodeJSON, fourten.DecodeJSON)
+
+	t.Run("Get decodes into the type", func(t *testing.T) {
+		u, res, err := fourten.Get[user](ctx, client, "/users/:id", fourten.Param("id", "1"))
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(res.StatusCode, http.StatusOK))
+		assert.Check(t, cmp.Equal(u, user{ID: "1", Name: "GET"}))
+		assert.Check(t, cmp.Equal(lastBody, ""))
+	})
+
+	t.Run("Post encodes the input", func(t *testing.T) {
+		u, _, err := fourten.Post[user, user](ctx, client, "/users/2", user{Name: "new"})
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(u, user{ID: "2", Name: "POST"}))
+		assert.Check(t, cmp.Equal(lastBody, "{\"id\":\"\",\"name\":\"new\"}\n"))
+	})
+
+	t.Run("Empty output decodes nothing", func(t *testing.T) {
+		_, res, err := fourten.Delete[fourten.Empty](ctx, client, "/users/3")
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(res.StatusCode, http.StatusNoContent))
+	})
+
+	t.Run("endpoints can be declared once", func(t *testing.T) {
+		getUser := fourten.Endpoint[fourten.Empty, user]{Method: "GET", Path: "/users/:id"}
+		updateUser := fourten.Endpoint[user, user]{Method: "PUT", Path: "/users/:id"}
+
+		u, _, err := getUser.Call(ctx, client, fourten.Empty{}, fourten.Param("id", "4"))
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(u, user{ID: "4", Name: "GET"}))
+
+		u, _, err = updateUser.Call(ctx, client, user{Name: "x"}, fourten.Param("id", "5"))
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(u, user{ID: "5", Name: "PUT"}))
+	})
+
+	t.Run("errors are the same as Call", func(t *testing.T) {
+		_, _, err := fourten.Get[user](ctx, client.Derive(fourten.DontDecode), "/users/6")
+		assert.Check(t, cmp.ErrorContains(err, "no decoder configured"))
+	})
+}
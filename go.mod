@@ -1,6 +1,6 @@
 module github.com/glenjamin/fourten
 
-go 1.12
+go 1.18
 
 require (
 	github.com/NYTimes/gziphandler v1.1.1
@@ -8,3 +8,5 @@ require (
 	github.com/google/go-cmp v0.3.0
 	gotest.tools/v3 v3.0.2
 )
+
+require github.com/pkg/errors v0.8.1 // indirect
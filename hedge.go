@@ -0,0 +1,120 @@
+package fourten
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+type hedgePolicy struct {
+	after time.Duration
+	max   int
+}
+
+// Hedge sends a duplicate GET or HEAD request if no response headers have arrived within after,
+// using whichever response arrives first. Up to max duplicates are sent, each after another delay,
+// and the losers are cancelled. Any rate limit also applies to duplicates, which are skipped if it has been reached.
+// If every request sent so far fails the attempt fails, rather than sending another.
+// Hedging doesn't apply to Stream.
+func Hedge(after time.Duration, max int) Option {
+	return func(c *Client) {
+		if !c.validDuration("Hedge after", after) || !c.validCount("Hedge max", max, 0) {
+			return
+		}
+		c.hedge = hedgePolicy{after: after, max: max}
+	}
+}
+
+// DontHedge stops sending duplicate requests
+func DontHedge(c *Client) {
+	c.hedge = hedgePolicy{}
+}
+
+func (p hedgePolicy) applies(req *http.Request) bool {
+	if p.max <= 0 {
+		return false
+	}
+	switch req.Method {
+	case "GET", "HEAD":
+		return true
+	}
+	return false
+}
+
+type hedgeResult struct {
+	index int
+	res   *http.Response
+	err   error
+}
+
+// hedgedSend is like send, but races duplicate requests against each other when hedging is enabled.
+// The winner's context is a child of ctx, so lives as long as ctx does.
+func (c *Client) hedgedSend(ctx context.Context, req *http.Request, target string) (*http.Response, error) {
+	if !c.hedge.applies(req) {
+		return c.send(ctx, req)
+	}
+
+	total := c.hedge.max + 1
+	results := make(chan hedgeResult, total)
+	cancels := make([]context.CancelFunc, 0, total)
+	launch := func() {
+		hedgeCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		// copied here, as the losers may still be starting when a retry points req somewhere else
+		hedgeReq := req.WithContext(hedgeCtx)
+		go func(index int) {
+			res, err := c.send(hedgeCtx, hedgeReq)
+			results <- hedgeResult{index: index, res: res, err: err}
+		}(len(cancels) - 1)
+	}
+
+	timer := time.NewTimer(c.hedge.after)
+	defer timer.Stop()
+
+	launch()
+	inFlight := 1
+	var lastErr error
+	for {
+		select {
+		case <-timer.C:
+			if len(cancels) < total {
+				// each hedge is a real request, so must fit within the rate limit, but isn't worth waiting for
+				if c.tryRateLimit(target) {
+					launch()
+					inFlight++
+				}
+				timer.Reset(c.hedge.after)
+			}
+		case result := <-results:
+			inFlight--
+			if result.err != nil {
+				lastErr = result.err
+				if inFlight > 0 {
+					continue
+				}
+				// sending another request now would be a retry, which is up to the retry policy
+				return nil, lastErr
+			}
+			for i, cancel := range cancels {
+				if i != result.index {
+					cancel()
+				}
+			}
+			go drainHedges(results, inFlight)
+			return result.res, nil
+		}
+	}
+}
+
+// drainHedges waits for the losing requests to finish, freeing up their connections
+func drainHedges(results <-chan hedgeResult, n int) {
+	for i := 0; i < n; i++ {
+		result := <-results
+		if result.res != nil {
+			_, _ = io.Copy(ioutil.Discard, result.res.Body)
+			result.res.Body.Close()
+		}
+	}
+}
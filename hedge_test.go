@@ -0,0 +1,175 @@
+package fourten_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestHedging(t *testing.T) {
+	// slowFirstServer makes the first request it receives slow, and responds to the rest quickly.
+	// Each response says which request it was, and the number of requests received is returned.
+	slowFirstServer := func(t *testing.T, delay time.Duration) (string, *int32) {
+		var requests int32
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&requests, 1)
+			if n == 1 {
+				select {
+				case <-time.After(delay):
+				case <-r.Context().Done():
+					return
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Request", fmt.Sprint(n))
+			_, _ = fmt.Fprintf(w, `{"request": %d}`, n)
+		}))
+		t.Cleanup(slow.Close)
+		return slow.URL, &requests
+	}
+
+	t.Run("uses the first response to arrive", func(t *testing.T) {
+		url, requests := slowFirstServer(t, time.Second)
+		client := fourten.New(fourten.BaseURL(url), fourten.DecodeJSON,
+			fourten.Hedge(10*time.Millisecond, 2))
+
+		var output struct{ Request int }
+		start := time.Now()
+		res, err := client.GET(ctx, "/hedged", &output)
+		assert.NilError(t, err)
+		assert.Check(t, time.Since(start) < 500*time.Millisecond)
+		assert.Check(t, cmp.Equal(output.Request, 2))
+		assert.Check(t, cmp.Equal(res.Header.Get("X-Request"), "2"))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(2)), "no need for the second hedge")
+	})
+
+	t.Run("doesn't hedge when the response is quick enough", func(t *testing.T) {
+		url, requests := slowFirstServer(t, 0)
+		client := fourten.New(fourten.BaseURL(url), fourten.Hedge(100*time.Millisecond, 2))
+
+		res, err := client.GET(ctx, "/hedged", nil)
+		assert.NilError(t, err)
+		res.Body.Close()
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(1)))
+	})
+
+	t.Run("doesn't hedge non-GET requests", func(t *testing.T) {
+		url, requests := slowFirstServer(t, 50*time.Millisecond)
+		client := fourten.New(fourten.BaseURL(url), fourten.Hedge(time.Millisecond, 2))
+
+		res, err := client.PUT(ctx, "/hedged", nil, nil)
+		assert.NilError(t, err)
+		res.Body.Close()
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(1)))
+	})
+
+	t.Run("fails without hedging again once everything has failed", func(t *testing.T) {
+		var requests int32
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			// drop the connection without responding
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}))
+		defer failing.Close()
+		client := fourten.New(fourten.BaseURL(failing.URL), fourten.Hedge(time.Minute, 1))
+
+		_, err := client.GET(ctx, "/hedged", nil)
+		assert.Check(t, cmp.ErrorContains(err, "EOF"))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(&requests), int32(1)))
+	})
+
+	t.Run("leaves trying again to the retry policy", func(t *testing.T) {
+		var requests int32
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			w.WriteHeader(204)
+		}))
+		defer failing.Close()
+		// a dropped connection isn't retried by default, so retry anything once
+		var retries int
+		client := fourten.New(fourten.BaseURL(failing.URL), fourten.Hedge(time.Minute, 1),
+			fourten.RetryStrategy(func() fourten.Retrier {
+				return func(error) time.Duration {
+					if retries++; retries > 1 {
+						return -1
+					}
+					return time.Millisecond
+				}
+			}))
+
+		res, err := client.GET(ctx, "/hedged", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(res.StatusCode, 204))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(&requests), int32(2)))
+	})
+
+	t.Run("retries can move to another backend while losing duplicates are still in flight", func(t *testing.T) {
+		var requests int32
+		flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch atomic.AddInt32(&requests, 1) {
+			case 1:
+				select {
+				case <-time.After(50 * time.Millisecond):
+				case <-r.Context().Done():
+				}
+			case 2:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		t.Cleanup(flaky.Close)
+		client := fourten.New(fourten.BaseURLs(flaky.URL, flaky.URL), fourten.Hedge(5*time.Millisecond, 1),
+			fourten.RetryMaxAttempts(2), fourten.RetryBackoff(time.Millisecond, time.Millisecond, 1, 0))
+
+		res, err := client.GET(ctx, "/hedged", nil)
+		assert.NilError(t, err)
+		res.Body.Close()
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(&requests), int32(3)))
+	})
+
+	t.Run("duplicates count towards the rate limit", func(t *testing.T) {
+		url, requests := slowFirstServer(t, 100*time.Millisecond)
+		client := fourten.New(fourten.BaseURL(url), fourten.Hedge(10*time.Millisecond, 2),
+			fourten.RateLimit(0.001, 2))
+
+		res, err := client.GET(ctx, "/hedged", nil)
+		assert.NilError(t, err)
+		res.Body.Close()
+		assert.Check(t, cmp.Equal(res.Header.Get("X-Request"), "2"))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(2)), "only one token left for hedging")
+	})
+
+	t.Run("skips duplicates when the rate limit has been reached", func(t *testing.T) {
+		url, requests := slowFirstServer(t, 50*time.Millisecond)
+		client := fourten.New(fourten.BaseURL(url), fourten.Hedge(5*time.Millisecond, 2),
+			fourten.RateLimitPerRoute(0.001, 1))
+
+		res, err := client.GET(ctx, "/hedged", nil)
+		assert.NilError(t, err)
+		res.Body.Close()
+		assert.Check(t, cmp.Equal(res.Header.Get("X-Request"), "1"))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(1)))
+	})
+
+	t.Run("derived clients can opt out", func(t *testing.T) {
+		url, requests := slowFirstServer(t, 50*time.Millisecond)
+		client := fourten.New(fourten.BaseURL(url), fourten.Hedge(time.Millisecond, 2))
+
+		res, err := client.Derive(fourten.DontHedge).GET(ctx, "/hedged", nil)
+		assert.NilError(t, err)
+		res.Body.Close()
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(1)))
+	})
+}
@@ -0,0 +1,65 @@
+package fourten
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// CallIdempotencyKey sends key as the Idempotency-Key header of a single Call, which allows a POST or PATCH
+// to be retried. The same key is used across all attempts and redirects, and any generated key is replaced.
+func CallIdempotencyKey(key string) CallOption {
+	return CallHeader(idempotencyKeyHeader, key)
+}
+
+// GenerateIdempotencyKeys sends a random UUID as the Idempotency-Key header of every POST and PATCH Call,
+// which allows them to be retried. The same key is used across all attempts and redirects of a Call.
+func GenerateIdempotencyKeys(c *Client) {
+	c.idempotencyKeys = newUUID
+}
+
+// IdempotencyKeyGenerator is like GenerateIdempotencyKeys, but uses generate to produce each key
+func IdempotencyKeyGenerator(generate func() string) Option {
+	return func(c *Client) {
+		c.idempotencyKeys = generate
+	}
+}
+
+// DontGenerateIdempotencyKeys stops generating keys, although a CallIdempotencyKey will still be sent
+func DontGenerateIdempotencyKeys(c *Client) {
+	c.idempotencyKeys = nil
+}
+
+// setupIdempotencyKey generates a key for the Call if it needs one and doesn't already have one
+func (c *Client) setupIdempotencyKey(req *http.Request) {
+	if c.idempotencyKeys == nil || isIdempotent(req.Method) || req.Header.Get(idempotencyKeyHeader) != "" {
+		return
+	}
+	req.Header.Set(idempotencyKeyHeader, c.idempotencyKeys())
+}
+
+// isRetryable reports whether the request can safely be retried,
+// non-idempotent requests are fine to repeat if the server can use the key to spot duplicates
+func isRetryable(req *http.Request) bool {
+	if isIdempotent(req.Method) {
+		return true
+	}
+	switch req.Method {
+	case "POST", "PATCH":
+		return req.Header.Get(idempotencyKeyHeader) != ""
+	}
+	return false
+}
+
+// newUUID produces a random (version 4) UUID, as per RFC 4122 section 4.4
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,123 @@
+package fourten_test
+
+import (
+	"net/http"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	fastRetries := func(c *fourten.Client) {
+		fourten.RetryMaxAttempts(3)(c)
+		fourten.RetryBackoff(time.Millisecond, time.Millisecond, 1, 0)(c)
+	}
+
+	// recordKeys makes the server fail the first request, and notes the Idempotency-Key of each request
+	recordKeys := func(t *testing.T) *[]string {
+		var keys []string
+		respondWithStatuses(t, 500, 200)
+		handler := server.Handler
+		server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys = append(keys, r.Header.Get("Idempotency-Key"))
+			handler.ServeHTTP(w, r)
+		})
+		return &keys
+	}
+
+	t.Run("POST with a fixed key is retried using the same key", func(t *testing.T) {
+		keys := recordKeys(t)
+		client := fourten.New(fourten.BaseURL(server.URL), fastRetries)
+
+		_, err := client.POST(ctx, "/payments", nil, nil, fourten.CallIdempotencyKey("abc-123"))
+		assert.NilError(t, err)
+		assert.Check(t, cmp.DeepEqual(*keys, []string{"abc-123", "abc-123"}))
+	})
+
+	t.Run("generated keys are UUIDs, shared by all attempts of a Call", func(t *testing.T) {
+		keys := recordKeys(t)
+		client := fourten.New(fourten.BaseURL(server.URL), fastRetries, fourten.GenerateIdempotencyKeys)
+
+		_, err := client.PATCH(ctx, "/payments/1", nil, nil)
+		assert.NilError(t, err)
+		assert.Assert(t, cmp.Len(*keys, 2))
+		assert.Check(t, cmp.Regexp(
+			regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), (*keys)[0]))
+		assert.Check(t, cmp.Equal((*keys)[0], (*keys)[1]))
+
+		_, err = client.POST(ctx, "/payments", nil, nil)
+		assert.NilError(t, err)
+		assert.Check(t, (*keys)[2] != (*keys)[0], "each Call gets a new key")
+	})
+
+	t.Run("keys can come from a custom generator", func(t *testing.T) {
+		keys := recordKeys(t)
+		var generated int32
+		client := fourten.New(fourten.BaseURL(server.URL), fastRetries,
+			fourten.IdempotencyKeyGenerator(func() string {
+				atomic.AddInt32(&generated, 1)
+				return "custom"
+			}))
+
+		_, err := client.POST(ctx, "/payments", nil, nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.DeepEqual(*keys, []string{"custom", "custom"}))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(&generated), int32(1)))
+	})
+
+	t.Run("idempotent methods don't get generated keys", func(t *testing.T) {
+		keys := recordKeys(t)
+		client := fourten.New(fourten.BaseURL(server.URL), fastRetries, fourten.GenerateIdempotencyKeys)
+
+		_, err := client.PUT(ctx, "/payments/1", nil, nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.DeepEqual(*keys, []string{"", ""}))
+	})
+
+	t.Run("a key for the Call is used instead of a generated one", func(t *testing.T) {
+		keys := recordKeys(t)
+		client := fourten.New(fourten.BaseURL(server.URL), fastRetries, fourten.GenerateIdempotencyKeys)
+
+		_, err := client.POST(ctx, "/payments", nil, nil, fourten.CallIdempotencyKey("order-42"))
+		assert.NilError(t, err)
+		assert.Check(t, cmp.DeepEqual(*keys, []string{"order-42", "order-42"}))
+	})
+
+	t.Run("POST without a key is not retried", func(t *testing.T) {
+		keys := recordKeys(t)
+		client := fourten.New(fourten.BaseURL(server.URL), fastRetries,
+			fourten.GenerateIdempotencyKeys, fourten.DontGenerateIdempotencyKeys)
+
+		_, err := client.POST(ctx, "/payments", nil, nil)
+		assert.Check(t, cmp.ErrorContains(err, "HTTP Status 500"))
+		assert.Check(t, cmp.DeepEqual(*keys, []string{""}))
+	})
+
+	t.Run("the key is kept across redirects", func(t *testing.T) {
+		var keys []string
+		server.Sticky = true
+		server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys = append(keys, r.Header.Get("Idempotency-Key"))
+			if r.URL.Path == "/old" {
+				http.Redirect(w, r, "/new", http.StatusPermanentRedirect)
+			}
+		})
+		t.Cleanup(func() {
+			server.Sticky = false
+			server.Handler = nil
+		})
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.GenerateIdempotencyKeys)
+
+		_, err := client.POST(ctx, "/old", nil, nil)
+		assert.NilError(t, err)
+		assert.Assert(t, cmp.Len(keys, 2))
+		assert.Check(t, keys[0] != "")
+		assert.Check(t, cmp.Equal(keys[0], keys[1]))
+	})
+}
@@ -3,7 +3,10 @@ package fourten
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
+	"net/url"
+	"syscall"
 	"testing"
 	"time"
 
@@ -35,3 +38,243 @@ func TestTimeout_DefaultsToShort(t *testing.T) {
 	assert.Assert(t, deadline.Before(twoSecondsAhead),
 		"expected deadline to be short: %v < %v", deadline, twoSecondsAhead)
 }
+
+func TestStream_HeadersArrivingAsTheTimeoutFires(t *testing.T) {
+	client := New(RequestTimeout(10 * time.Millisecond))
+
+	// the response only turns up once the timeout has already cancelled the request
+	body := &closeRecorder{}
+	client.httpClient.Transport = roundTripFn(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return &http.Response{StatusCode: 200, Header: http.Header{}, Body: body, Request: req}, nil
+	})
+
+	res, err := client.Stream(context.Background(), "GET", "http://example.com/stream", nil)
+	assert.Assert(t, res == nil)
+	assert.Assert(t, errors.Is(err, ErrRequestTimeout), "%v", err)
+	assert.Assert(t, body.closed)
+}
+
+type closeRecorder struct {
+	closed bool
+}
+
+func (r *closeRecorder) Read([]byte) (int, error) { return 0, context.Canceled }
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestSRVURLs(t *testing.T) {
+	urls := srvURLs("https", []*net.SRV{
+		{Target: "a.example.com.", Port: 8443},
+		{Target: "b.example.com", Port: 443},
+		{Target: "::1", Port: 8080},
+	})
+	var bases []string
+	for _, u := range urls {
+		bases = append(bases, u.String())
+	}
+	assert.DeepEqual(t, bases, []string{
+		"https://a.example.com:8443",
+		"https://b.example.com:443",
+		"https://[::1]:8080",
+	})
+}
+
+func TestTransport_SharedWithDerivedClientsUntilModified(t *testing.T) {
+	parent := New()
+	shared := parent.Derive(RequestTimeout(time.Minute))
+	assert.Assert(t, shared.transport == parent.transport)
+
+	modified := parent.Derive(ResponseTimeout(time.Minute))
+	assert.Assert(t, modified.transport != parent.transport)
+	assert.Assert(t, modified.httpClient.Transport == modified.transport)
+	assert.Equal(t, modified.transport.ResponseHeaderTimeout, time.Minute)
+	assert.Equal(t, parent.transport.ResponseHeaderTimeout, time.Duration(0))
+
+	// Once it has its own copy, further changes don't need another one
+	transport := modified.transport
+	TLSHandshakeTimeout(time.Minute)(modified)
+	assert.Assert(t, modified.transport == transport)
+}
+
+func TestClassifyTimeout_ConnectTimeout(t *testing.T) {
+	dialErr := &url.Error{Op: "Get", URL: "http://example.com", Err: &net.OpError{
+		Op:  "dial",
+		Net: "tcp",
+		Err: timeoutErr{},
+	}}
+
+	err := classifyTimeout(context.Background(), false, dialErr)
+	assert.Assert(t, errors.Is(err, ErrConnectTimeout))
+	assert.Assert(t, errors.Is(err, dialErr.Err))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = classifyTimeout(canceled, false, dialErr)
+	assert.Assert(t, !errors.Is(err, ErrConnectTimeout), "parent context takes precedence")
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestClassifyTransport(t *testing.T) {
+	req := &http.Request{Method: "GET", URL: &url.URL{Scheme: "http", Host: "example.com"}}
+
+	resetErr := &url.Error{Op: "Get", URL: "http://example.com", Err: &net.OpError{
+		Op:  "read",
+		Net: "tcp",
+		Err: syscall.ECONNRESET,
+	}}
+	err := classifyTransport(req, resetErr)
+	assert.Assert(t, errors.Is(err, ErrConnectionReset))
+	assert.Equal(t, err.Error(), resetErr.Error())
+
+	unknownErr := &url.Error{Op: "Get", URL: "http://example.com", Err: errors.New("something odd")}
+	err = classifyTransport(req, unknownErr)
+	assert.Assert(t, AsTransportError(err) != nil)
+	assert.Assert(t, AsTransportError(err).Kind == nil)
+
+	bodyErr := errors.New("failed to read request body")
+	err = classifyTransport(req, bodyErr)
+	assert.Assert(t, err == bodyErr, "errors not from the http.Client are left alone")
+}
+
+func TestConnectionPoolOptions(t *testing.T) {
+	client := New(
+		MaxIdleConns(10),
+		MaxIdleConnsPerHost(5),
+		MaxConnsPerHost(20),
+		IdleConnTimeout(time.Minute),
+		DisableKeepAlives,
+		DisableHTTP2,
+	)
+	assert.Equal(t, client.transport.MaxIdleConns, 10)
+	assert.Equal(t, client.transport.MaxIdleConnsPerHost, 5)
+	assert.Equal(t, client.transport.MaxConnsPerHost, 20)
+	assert.Equal(t, client.transport.IdleConnTimeout, time.Minute)
+	assert.Assert(t, client.transport.DisableKeepAlives)
+	assert.Assert(t, !client.transport.ForceAttemptHTTP2)
+	assert.Assert(t, client.transport.TLSNextProto != nil)
+
+	derived := client.Derive(ForceHTTP2)
+	assert.Assert(t, derived.transport.ForceAttemptHTTP2)
+	assert.Assert(t, derived.transport.TLSNextProto == nil)
+	assert.Assert(t, !client.transport.ForceAttemptHTTP2, "parent is unaffected")
+}
+
+func TestSeparateConnections(t *testing.T) {
+	parent := New(MaxIdleConnsPerHost(50))
+	separate := parent.Derive(SeparateConnections)
+	assert.Assert(t, separate.transport != parent.transport)
+	assert.Equal(t, separate.transport.MaxIdleConnsPerHost, 50, "settings are inherited")
+
+	// New clients already have their own, so don't need another
+	fresh := New()
+	transport := fresh.transport
+	SeparateConnections(fresh)
+	assert.Assert(t, fresh.transport == transport)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 11, 19, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		header string
+		delay  time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"0", 0, true},
+		{"-5", 0, false},
+		{"soon", 0, false},
+		{"Thu, 19 Nov 2020 12:00:30 GMT", 30 * time.Second, true},
+		{"Thu, 19 Nov 2020 11:00:00 GMT", 0, true},
+	} {
+		delay, ok := parseRetryAfter(tc.header, now)
+		assert.Check(t, delay == tc.delay && ok == tc.ok,
+			"%q: expected %v %v, got %v %v", tc.header, tc.delay, tc.ok, delay, ok)
+	}
+}
+
+func TestBreaker_RollingWindow(t *testing.T) {
+	now := time.Date(2020, 11, 19, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	client := New(CircuitBreaker(CircuitBreakerSettings{
+		FailureRate: 0.5,
+		MinRequests: 4,
+		Window:      10 * time.Second,
+		OpenFor:     time.Second,
+	}))
+	client.breakers.now = clock
+	b := client.breakers.forURL(&url.URL{Scheme: "http", Host: "example.com"})
+	failure := &TransportError{Kind: ErrConnectionRefused, Err: errors.New("refused")}
+
+	attempt := func(err error) error {
+		record, allowErr := b.allow()
+		if allowErr != nil {
+			return allowErr
+		}
+		record(err)
+		return nil
+	}
+
+	// Old failures fall out of the window
+	assert.NilError(t, attempt(failure))
+	assert.NilError(t, attempt(failure))
+	assert.NilError(t, attempt(failure))
+	now = now.Add(15 * time.Second)
+	assert.NilError(t, attempt(failure))
+	assert.NilError(t, attempt(nil))
+	assert.Equal(t, b.state, breakerClosed)
+
+	// Failure rate over the minimum opens the circuit
+	assert.NilError(t, attempt(failure))
+	assert.NilError(t, attempt(failure))
+	assert.Equal(t, b.state, breakerOpen)
+	assert.Assert(t, errors.Is(attempt(nil), ErrCircuitOpen))
+
+	// A failed trial opens it again
+	now = now.Add(time.Second)
+	assert.NilError(t, attempt(failure))
+	assert.Equal(t, b.state, breakerOpen)
+
+	// Only one trial at a time is let through, and cancelled trials don't count
+	now = now.Add(time.Second)
+	record, err := b.allow()
+	assert.NilError(t, err)
+	_, err = b.allow()
+	assert.Assert(t, errors.Is(err, ErrCircuitOpen))
+	record(&TransportError{Kind: ErrCanceled, Err: errors.New("canceled")})
+	assert.Equal(t, b.state, breakerHalfOpen)
+
+	assert.NilError(t, attempt(nil))
+	assert.Equal(t, b.state, breakerClosed)
+}
+
+func TestParseQuota(t *testing.T) {
+	now := time.Date(2020, 11, 19, 12, 0, 0, 0, time.UTC)
+
+	quota, ok := parseQuota(http.Header{
+		"Ratelimit-Limit":     {"100, 100;w=60"},
+		"Ratelimit-Remaining": {"99"},
+		"Ratelimit-Reset":     {"60"},
+	}, now)
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, quota, Quota{Limit: 100, Remaining: 99, ResetAt: now.Add(time.Minute)})
+
+	quota, ok = parseQuota(http.Header{
+		"X-Ratelimit-Limit":     {"5000"},
+		"X-Ratelimit-Remaining": {"4999"},
+		"X-Ratelimit-Reset":     {"1605787260"},
+	}, now)
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, quota, Quota{Limit: 5000, Remaining: 4999, ResetAt: time.Unix(1605787260, 0)})
+
+	_, ok = parseQuota(http.Header{"Ratelimit-Limit": {"100"}}, now)
+	assert.Assert(t, !ok, "remaining is required")
+}
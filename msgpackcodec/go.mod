@@ -0,0 +1,15 @@
+module github.com/glenjamin/fourten/msgpackcodec
+
+go 1.18
+
+require (
+	github.com/glenjamin/fourten v0.0.0-20261016062755-89c57e78af0d
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	gotest.tools/v3 v3.0.2
+)
+
+require (
+	github.com/google/go-cmp v0.3.0 // indirect
+	github.com/pkg/errors v0.8.1 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+)
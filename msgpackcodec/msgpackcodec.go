@@ -0,0 +1,63 @@
+// Package msgpackcodec encodes and decodes fourten requests and responses as MessagePack.
+// It lives in its own module so that the MessagePack dependency is only needed by clients which use it.
+package msgpackcodec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/glenjamin/fourten"
+)
+
+// ContentType is the content type sent with MessagePack request bodies
+const ContentType = "application/msgpack"
+
+// Encode sends request bodies as MessagePack
+func Encode(c *fourten.Client) {
+	fourten.EncodeWith(Encoder)(c)
+}
+
+// Decode decodes responses as MessagePack, accepting application/msgpack and application/x-msgpack
+func Decode(c *fourten.Client) {
+	fourten.DecodeWith(Decoder, ContentType)(c)
+}
+
+// Encoder is the fourten.Encoder used by Encode
+func Encoder(input interface{}) (fourten.RequestEncoding, error) {
+	b, err := msgpack.Marshal(input)
+	if err != nil {
+		return fourten.RequestEncoding{}, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", ContentType)
+	return fourten.RequestEncoding{
+		ContentLength: int64(len(b)),
+		GetBody: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		},
+		Header: header,
+	}, nil
+}
+
+// Decoder is the fourten.Decoder used by Decode
+func Decoder(contentType string, r io.Reader, target interface{}) error {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != ContentType && mediaType != "application/x-msgpack" {
+		return errors.New("expected MessagePack content-type, got " + contentType)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read: %w", err)
+	}
+	if err := msgpack.Unmarshal(b, target); err != nil {
+		return fmt.Errorf("failed to decode: %w", err)
+	}
+	return nil
+}
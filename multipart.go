@@ -0,0 +1,247 @@
+package fourten
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// EncodeMultipart sends request bodies as multipart/form-data, the input must be a *Multipart.
+// Files are streamed rather than read into memory, and re-read for each attempt or redirect.
+func EncodeMultipart(c *Client) {
+	c.setEncoder("EncodeMultipart", multipartEncoder)
+}
+
+// Multipart is the input for EncodeMultipart, made up of fields and files
+type Multipart struct {
+	Parts []Part
+}
+
+// Part is a single field or file in a Multipart body.
+// The content comes from the first of Path, Reader or Value which is set.
+type Part struct {
+	// Name is the form field name
+	Name string
+	// Filename is sent for file parts, and defaults to the base name of Path
+	Filename string
+	// ContentType defaults to application/octet-stream for files, and is left out for other fields
+	ContentType string
+	// Header holds any other headers to send with the part
+	Header textproto.MIMEHeader
+
+	// Path is a file to read the content from, which is opened afresh for each attempt
+	Path string
+	// Reader provides the content, it can only be sent more than once if it is also an io.Seeker
+	Reader io.Reader
+	// Value is the content of a plain field
+	Value string
+}
+
+// Field adds a plain form field
+func (m *Multipart) Field(name, value string) *Multipart {
+	return m.Add(Part{Name: name, Value: value})
+}
+
+// File adds a file whose content comes from r
+func (m *Multipart) File(name, filename string, r io.Reader) *Multipart {
+	return m.Add(Part{Name: name, Filename: filename, Reader: r})
+}
+
+// FileFromPath adds the file at path
+func (m *Multipart) FileFromPath(name, path string) *Multipart {
+	return m.Add(Part{Name: name, Path: path})
+}
+
+// Add adds a part, for when more control is needed over its headers
+func (m *Multipart) Add(part Part) *Multipart {
+	m.Parts = append(m.Parts, part)
+	return m
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func (p Part) isFile() bool {
+	return p.Path != "" || p.Reader != nil || p.Filename != ""
+}
+
+func (p Part) header() textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	for k, v := range p.Header {
+		h[k] = v
+	}
+	disposition := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(p.Name))
+	filename := p.Filename
+	if filename == "" && p.Path != "" {
+		filename = filepath.Base(p.Path)
+	}
+	if p.isFile() {
+		disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(filename))
+	}
+	h.Set("Content-Disposition", disposition)
+	switch {
+	case p.ContentType != "":
+		h.Set("Content-Type", p.ContentType)
+	case p.isFile():
+		h.Set("Content-Type", "application/octet-stream")
+	}
+	return h
+}
+
+func multipartEncoder(input interface{}) (RequestEncoding, error) {
+	m, ok := input.(*Multipart)
+	if !ok {
+		return RequestEncoding{}, fmt.Errorf("cannot encode %T as multipart, expected *fourten.Multipart", input)
+	}
+
+	// the boundaries and part headers are written up front, leaving gaps for the content of each part
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	framing := make([][]byte, 0, len(m.Parts)+1)
+	sources := make([]*partSource, 0, len(m.Parts))
+	length := int64(0)
+	for _, part := range m.Parts {
+		if _, err := w.CreatePart(part.header()); err != nil {
+			return RequestEncoding{}, err
+		}
+		framing = append(framing, append([]byte(nil), buf.Bytes()...))
+		if length >= 0 {
+			length += int64(buf.Len())
+		}
+		buf.Reset()
+
+		source, err := newPartSource(part)
+		if err != nil {
+			return RequestEncoding{}, fmt.Errorf("failed to read part %s: %w", part.Name, err)
+		}
+		sources = append(sources, source)
+		if source.size < 0 {
+			length = -1
+		} else if length >= 0 {
+			length += source.size
+		}
+	}
+	if err := w.Close(); err != nil {
+		return RequestEncoding{}, err
+	}
+	framing = append(framing, buf.Bytes())
+	if length >= 0 {
+		length += int64(buf.Len())
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", w.FormDataContentType())
+	return RequestEncoding{
+		ContentLength: length,
+		GetBody: func() (io.ReadCloser, error) {
+			body := &multipartBody{}
+			readers := make([]io.Reader, 0, len(framing)+len(sources))
+			for i, source := range sources {
+				r, err := source.open()
+				if err != nil {
+					body.Close()
+					return nil, fmt.Errorf("failed to read part %s: %w", m.Parts[i].Name, err)
+				}
+				if closer, ok := r.(io.Closer); ok {
+					body.closers = append(body.closers, closer)
+				}
+				readers = append(readers, bytes.NewReader(framing[i]), r)
+			}
+			readers = append(readers, bytes.NewReader(framing[len(framing)-1]))
+			body.Reader = io.MultiReader(readers...)
+			return body, nil
+		},
+		Header: header,
+	}, nil
+}
+
+// multipartBody reads each part in turn, closing any files once done
+type multipartBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *multipartBody) Close() error {
+	var first error
+	for _, closer := range b.closers {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	b.closers = nil
+	return first
+}
+
+var errPartNotReplayable = errors.New("part can't be sent again as its Reader is not an io.Seeker")
+
+// partSource provides the content of a part for each attempt, with its size if known up front or -1 if not
+type partSource struct {
+	size int64
+	open func() (io.Reader, error)
+}
+
+func newPartSource(part Part) (*partSource, error) {
+	switch {
+	case part.Path != "":
+		info, err := os.Stat(part.Path)
+		if err != nil {
+			return nil, err
+		}
+		return &partSource{size: info.Size(), open: func() (io.Reader, error) {
+			return os.Open(part.Path)
+		}}, nil
+	case part.Reader != nil:
+		return newReaderSource(part.Reader)
+	default:
+		return &partSource{size: int64(len(part.Value)), open: func() (io.Reader, error) {
+			return strings.NewReader(part.Value), nil
+		}}, nil
+	}
+}
+
+func newReaderSource(r io.Reader) (*partSource, error) {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		var mu sync.Mutex
+		used := false
+		source := &partSource{size: -1, open: func() (io.Reader, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if used {
+				return nil, errPartNotReplayable
+			}
+			used = true
+			// hide any Close method, as the caller is responsible for closing their own reader
+			return struct{ io.Reader }{r}, nil
+		}}
+		if sized, ok := r.(interface{ Len() int }); ok {
+			source.size = int64(sized.Len())
+		}
+		return source, nil
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return &partSource{size: end - start, open: func() (io.Reader, error) {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return io.LimitReader(r, end-start), nil
+	}}, nil
+}
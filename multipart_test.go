@@ -0,0 +1,176 @@
+package fourten_test
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+type receivedPart struct {
+	Name, Filename, ContentType, Extra, Content string
+}
+
+func TestEncodeMultipart(t *testing.T) {
+	var parts []receivedPart
+	var contentLength int64
+	var failFirst bool
+	uploads := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failFirst {
+			failFirst = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		contentLength = r.ContentLength
+		parts = nil
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			r.Body = gr
+		}
+		reader, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			b, _ := ioutil.ReadAll(part)
+			parts = append(parts, receivedPart{
+				Name:        part.FormName(),
+				Filename:    part.FileName(),
+				ContentType: part.Header.Get("Content-Type"),
+				Extra:       part.Header.Get("X-Extra"),
+				Content:     string(b),
+			})
+		}
+	}))
+	defer uploads.Close()
+	client := fourten.New(
+		fourten.BaseURL(uploads.URL),
+		fourten.EncodeMultipart,
+		fourten.RetryMaxAttempts(2),
+		fourten.RetryBackoff(time.Millisecond, time.Millisecond, 1, 0),
+	)
+
+	dir, err := ioutil.TempDir("", "fourten-multipart")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "report.csv")
+	assert.NilError(t, ioutil.WriteFile(path, []byte("a,b\n1,2\n"), 0600))
+
+	t.Run("sends fields and files", func(t *testing.T) {
+		body := (&fourten.Multipart{}).
+			Field("title", "Q1 \"report\"").
+			FileFromPath("report", path).
+			File("notes", "notes.txt", strings.NewReader("some notes")).
+			Add(fourten.Part{
+				Name:        "meta",
+				Value:       `{"a":1}`,
+				ContentType: "application/json",
+				Header:      textproto.MIMEHeader{"X-Extra": {"yes"}},
+			})
+		_, err := client.POST(ctx, "/upload", body, nil)
+		assert.NilError(t, err)
+
+		assert.Check(t, cmp.DeepEqual(parts, []receivedPart{
+			{Name: "title", Content: "Q1 \"report\""},
+			{Name: "report", Filename: "report.csv", ContentType: "application/octet-stream", Content: "a,b\n1,2\n"},
+			{Name: "notes", Filename: "notes.txt", ContentType: "application/octet-stream", Content: "some notes"},
+			{Name: "meta", ContentType: "application/json", Extra: "yes", Content: `{"a":1}`},
+		}))
+		assert.Check(t, contentLength > 0)
+	})
+
+	t.Run("streams readers of unknown length", func(t *testing.T) {
+		unsized := struct{ io.Reader }{strings.NewReader("streamed")}
+		_, err := client.POST(ctx, "/upload", (&fourten.Multipart{}).File("f", "f.bin", unsized), nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(contentLength, int64(-1)))
+		assert.Check(t, cmp.Equal(parts[0].Content, "streamed"))
+	})
+
+	t.Run("replays files and seekable readers for retries", func(t *testing.T) {
+		failFirst = true
+		body := (&fourten.Multipart{}).
+			FileFromPath("report", path).
+			File("notes", "notes.txt", strings.NewReader("some notes"))
+		_, err := client.PUT(ctx, "/upload", body, nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Len(parts, 2))
+		assert.Check(t, cmp.Equal(parts[0].Content, "a,b\n1,2\n"))
+		assert.Check(t, cmp.Equal(parts[1].Content, "some notes"))
+	})
+
+	t.Run("can't replay other readers", func(t *testing.T) {
+		failFirst = true
+		unsized := struct{ io.Reader }{strings.NewReader("once")}
+		_, err := client.PUT(ctx, "/upload", (&fourten.Multipart{}).File("f", "f.bin", unsized), nil)
+		assert.Check(t, cmp.ErrorContains(err, "not an io.Seeker"))
+	})
+
+	t.Run("needs a Multipart input", func(t *testing.T) {
+		_, err := client.POST(ctx, "/upload", map[string]string{"a": "b"}, nil)
+		assert.Check(t, cmp.ErrorContains(err, "expected *fourten.Multipart"))
+	})
+
+	t.Run("files are closed once gzipped", func(t *testing.T) {
+		if _, err := os.Stat("/proc/self/fd"); err != nil {
+			t.Skip("needs /proc to find open files")
+		}
+		big := filepath.Join(dir, "big.csv")
+		content := strings.Repeat("a,b\n1,2\n", 200)
+		assert.NilError(t, ioutil.WriteFile(big, []byte(content), 0600))
+		gzipping := client.Derive(fourten.GzipRequests)
+
+		_, err := gzipping.POST(ctx, "/upload", (&fourten.Multipart{}).FileFromPath("big", big), nil)
+		assert.NilError(t, err)
+		assert.Check(t, contentLength < int64(len(content)))
+		assert.Check(t, cmp.Len(parts, 1))
+		assert.Check(t, cmp.Equal(parts[0].Content, content))
+		assert.Check(t, cmp.Equal(openHandles(t, big), 0))
+	})
+
+	t.Run("missing files are reported", func(t *testing.T) {
+		_, err := client.POST(ctx, "/upload", (&fourten.Multipart{}).FileFromPath("f", filepath.Join(dir, "nope")), nil)
+		assert.Check(t, cmp.ErrorContains(err, "failed to read part f"))
+	})
+}
+
+// openHandles counts how many of this process's file descriptors refer to path
+func openHandles(t *testing.T, path string) int {
+	t.Helper()
+	path, err := filepath.EvalSymlinks(path)
+	assert.NilError(t, err)
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	assert.NilError(t, err)
+	count := 0
+	for _, fd := range fds {
+		if target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name())); err == nil && target == path {
+			count++
+		}
+	}
+	return count
+}
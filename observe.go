@@ -0,0 +1,96 @@
+package fourten
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RequestInfo describes a request attempt which is about to be made
+type RequestInfo struct {
+	Method string
+	// Target is the target passed to Call, before any URL parameters were filled in.
+	// This makes it suitable for use as a low-cardinality route name in metrics.
+	Target string
+	// URL is the fully resolved URL being requested
+	URL *url.URL
+	// Attempt counts up from 1, increasing each time the request is retried.
+	// It is 0 when no request could be made, such as when the bulkhead is full or the circuit is open.
+	Attempt int
+	// Queued is the time the Call spent waiting for a slot, when using MaxConcurrent
+	Queued time.Duration
+	// InFlight is the number of Calls holding a slot, including this one, when using MaxConcurrent
+	InFlight int
+}
+
+// ResponseInfo describes the outcome of a request attempt
+type ResponseInfo struct {
+	// StatusCode is 0 if no response was received
+	StatusCode int
+	// Response is nil if no response was received
+	Response *http.Response
+	// Err is whatever error the attempt produced, including HTTPErrors and decoding failures,
+	// or why no request could be made, such as ErrBulkheadFull or ErrCircuitOpen
+	Err error
+	// Attempt is 0 when no request could be made
+	Attempt int
+	// Duration is the time taken by this attempt
+	Duration time.Duration
+	// Elapsed is the time taken since the start of the Call, across all attempts so far
+	Elapsed time.Duration
+	// Quota is the rate limit quota reported by the server, nil if it didn't report one
+	Quota *Quota
+}
+
+// ResponseObserver is notified when a request attempt has completed
+type ResponseObserver func(res ResponseInfo)
+
+// Observer is notified before each request attempt, and can return a ResponseObserver
+// to be notified once that attempt has completed
+type Observer func(req RequestInfo) ResponseObserver
+
+// Observe adds an observer to the client, which will be notified of every request attempt.
+// Calls which are turned away without making a request are reported too, as attempt 0.
+// This can be used to add metrics, logging and tracing. Multiple observers can be added.
+func Observe(observer Observer) Option {
+	return func(c *Client) {
+		c.observers = append(c.observers[:len(c.observers):len(c.observers)], observer)
+	}
+}
+
+// DontObserve removes all observers from the client
+func DontObserve(c *Client) {
+	c.observers = nil
+}
+
+func noopResponseObserver(ResponseInfo) {}
+
+// observe notifies all of the observers about a request, returning a function to notify them of the response
+func (c *Client) observe(req RequestInfo) ResponseObserver {
+	if len(c.observers) == 0 {
+		return noopResponseObserver
+	}
+	responseObservers := make([]ResponseObserver, 0, len(c.observers))
+	for _, observer := range c.observers {
+		if ro := observer(req); ro != nil {
+			responseObservers = append(responseObservers, ro)
+		}
+	}
+	return func(res ResponseInfo) {
+		for _, ro := range responseObservers {
+			ro(res)
+		}
+	}
+}
+
+// observeRejection notifies the observers about a request which was turned away before it could be made
+func (c *Client) observeRejection(req *http.Request, target string, queued, elapsed time.Duration, err error) {
+	observed := c.observe(RequestInfo{
+		Method:   req.Method,
+		Target:   target,
+		URL:      req.URL,
+		Queued:   queued,
+		InFlight: c.inFlight(),
+	})
+	observed(ResponseInfo{Err: err, Elapsed: elapsed})
+}
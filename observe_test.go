@@ -0,0 +1,159 @@
+package fourten_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestObserve(t *testing.T) {
+	var requests []fourten.RequestInfo
+	var responses []fourten.ResponseInfo
+	recorder := fourten.Observe(func(req fourten.RequestInfo) fourten.ResponseObserver {
+		requests = append(requests, req)
+		return func(res fourten.ResponseInfo) {
+			responses = append(responses, res)
+		}
+	})
+	reset := func() {
+		requests = nil
+		responses = nil
+	}
+
+	t.Run("observes requests and responses", func(t *testing.T) {
+		reset()
+		client := fourten.New(fourten.BaseURL(server.URL), recorder)
+
+		_, err := client.GET(ctx, "/user/:user-id", nil, fourten.Param("user-id", "glenjamin"))
+		assert.NilError(t, err)
+
+		assert.Assert(t, cmp.Len(requests, 1))
+		assert.Check(t, cmp.Equal(requests[0].Method, "GET"))
+		assert.Check(t, cmp.Equal(requests[0].Target, "/user/:user-id"))
+		assert.Check(t, cmp.Equal(requests[0].URL.String(), server.URL+"/user/glenjamin"))
+		assert.Check(t, cmp.Equal(requests[0].Attempt, 1))
+
+		assert.Assert(t, cmp.Len(responses, 1))
+		assert.Check(t, cmp.Equal(responses[0].StatusCode, 200))
+		assert.Check(t, responses[0].Response != nil)
+		assert.Check(t, cmp.Nil(responses[0].Err))
+		assert.Check(t, cmp.Equal(responses[0].Attempt, 1))
+		assert.Check(t, responses[0].Duration > 0)
+		assert.Check(t, responses[0].Elapsed >= responses[0].Duration)
+	})
+
+	t.Run("observes HTTP errors", func(t *testing.T) {
+		reset()
+		client := fourten.New(fourten.BaseURL(server.URL), recorder)
+		server.Response = StubResponse{Status: 404}
+
+		_, err := client.GET(ctx, "/missing", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrHTTP))
+
+		assert.Assert(t, cmp.Len(responses, 1))
+		assert.Check(t, cmp.Equal(responses[0].StatusCode, 404))
+		assert.Check(t, errors.Is(responses[0].Err, fourten.ErrHTTP))
+	})
+
+	t.Run("observes decoding failures", func(t *testing.T) {
+		reset()
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.DecodeJSON, recorder)
+		server.Response = StubResponse{Status: 200, Body: "not json"}
+
+		var out interface{}
+		_, err := client.GET(ctx, "/data", &out)
+		assert.Check(t, cmp.ErrorContains(err, "expected JSON content-type"))
+
+		assert.Assert(t, cmp.Len(responses, 1))
+		assert.Check(t, cmp.Equal(responses[0].StatusCode, 200))
+		assert.Check(t, cmp.ErrorContains(responses[0].Err, "expected JSON content-type"))
+	})
+
+	t.Run("observes each retried attempt", func(t *testing.T) {
+		reset()
+		respondWithStatuses(t, 500, 502, 200)
+		client := fourten.New(fourten.BaseURL(server.URL), recorder,
+			fourten.RetryMaxAttempts(3), fourten.RetryBackoff(time.Millisecond, time.Millisecond, 1, 0))
+
+		_, err := client.GET(ctx, "/flaky", nil)
+		assert.NilError(t, err)
+
+		assert.Assert(t, cmp.Len(requests, 3))
+		assert.Assert(t, cmp.Len(responses, 3))
+		for i, status := range []int{500, 502, 200} {
+			assert.Check(t, cmp.Equal(requests[i].Attempt, i+1))
+			assert.Check(t, cmp.Equal(responses[i].Attempt, i+1))
+			assert.Check(t, cmp.Equal(responses[i].StatusCode, status))
+		}
+		assert.Check(t, responses[2].Elapsed > responses[2].Duration)
+	})
+
+	t.Run("observes calls turned away by an open circuit", func(t *testing.T) {
+		reset()
+		respondWithStatuses(t, 500)
+		client := fourten.New(fourten.BaseURL(server.URL), recorder,
+			fourten.CircuitBreaker(fourten.CircuitBreakerSettings{MinRequests: 1, OpenFor: time.Minute}))
+
+		_, err := client.GET(ctx, "/down", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrHTTP))
+		_, err = client.GET(ctx, "/down", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrCircuitOpen))
+
+		assert.Assert(t, cmp.Len(requests, 2))
+		assert.Assert(t, cmp.Len(responses, 2))
+		assert.Check(t, cmp.Equal(requests[1].Attempt, 0))
+		assert.Check(t, cmp.Equal(requests[1].Target, "/down"))
+		assert.Check(t, cmp.Equal(responses[1].Attempt, 0))
+		assert.Check(t, cmp.Equal(responses[1].StatusCode, 0))
+		assert.Check(t, errors.Is(responses[1].Err, fourten.ErrCircuitOpen))
+	})
+
+	t.Run("observes calls which gave up waiting for a slot", func(t *testing.T) {
+		reset()
+		client := fourten.New(fourten.BaseURL(server.URL), recorder, fourten.MaxConcurrent(1))
+		res, err := client.Stream(ctx, "GET", "/stream", nil)
+		assert.NilError(t, err)
+		defer res.Body.Close()
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = client.GET(cancelled, "/ping", nil)
+		assert.Check(t, errors.Is(err, context.Canceled))
+
+		assert.Assert(t, cmp.Len(responses, 2))
+		assert.Check(t, cmp.Equal(requests[1].Attempt, 0))
+		assert.Check(t, cmp.Equal(requests[1].InFlight, 1))
+		assert.Check(t, errors.Is(responses[1].Err, context.Canceled))
+	})
+
+	t.Run("derived clients add to inherited observers, and can remove them", func(t *testing.T) {
+		reset()
+		var extra int
+		client := fourten.New(fourten.BaseURL(server.URL), recorder)
+		derived := client.Derive(fourten.Observe(func(req fourten.RequestInfo) fourten.ResponseObserver {
+			extra++
+			return nil
+		}))
+
+		_, err := derived.GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Len(responses, 1))
+		assert.Check(t, cmp.Equal(extra, 1))
+
+		_, err = client.GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Len(responses, 2))
+		assert.Check(t, cmp.Equal(extra, 1))
+
+		_, err = derived.Derive(fourten.DontObserve).GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Len(responses, 2))
+		assert.Check(t, cmp.Equal(extra, 1))
+	})
+}
@@ -0,0 +1,131 @@
+package fourten
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OptionsError collects every problem found while applying options to a Client
+type OptionsError struct {
+	Errs []error
+}
+
+func (e *OptionsError) Error() string {
+	msgs := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		msgs = append(msgs, err.Error())
+	}
+	return "invalid options: " + strings.Join(msgs, "; ")
+}
+
+// Is allows errors.Is to match any of the collected errors
+func (e *OptionsError) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As allows errors.As to find any of the collected errors
+func (e *OptionsError) As(target interface{}) bool {
+	for _, err := range e.Errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap exposes the collected errors to anything else which understands multiple wrapped errors
+func (e *OptionsError) Unwrap() []error {
+	return e.Errs
+}
+
+// optionState tracks what the options being applied have done so far, so they can spot problems
+type optionState struct {
+	errs       []error
+	encoderSet bool
+}
+
+// NewE is like New, but returns an error describing any invalid options instead of panicking
+func NewE(opts ...Option) (*Client, error) {
+	c := newClient()
+	if err := c.apply(opts); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DeriveE is like Derive, but returns an error describing any invalid options instead of panicking
+func (c *Client) DeriveE(opts ...Option) (*Client, error) {
+	derived := c.derive()
+	if err := derived.apply(opts); err != nil {
+		return nil, err
+	}
+	return derived, nil
+}
+
+func (c *Client) apply(opts []Option) error {
+	c.options = &optionState{}
+	defer func() { c.options = nil }()
+	for _, opt := range opts {
+		opt(c)
+	}
+	if len(c.options.errs) > 0 {
+		return &OptionsError{Errs: c.options.errs}
+	}
+	return nil
+}
+
+// invalid reports a problem with an option.
+// Options applied directly to a Client rather than via New or Derive have nowhere to report to, so panic.
+func (c *Client) invalid(format string, args ...interface{}) {
+	err := fmt.Errorf(format, args...)
+	if c.options == nil {
+		panic(err)
+	}
+	c.options.errs = append(c.options.errs, err)
+}
+
+// validDuration checks that a duration option isn't negative
+func (c *Client) validDuration(option string, d time.Duration) bool {
+	if d < 0 {
+		c.invalid("%s must not be negative, got %v", option, d)
+		return false
+	}
+	return true
+}
+
+// validCount checks that a numeric option is at least min
+func (c *Client) validCount(option string, n, min int) bool {
+	if n < min {
+		c.invalid("%s must be at least %d, got %d", option, min, n)
+		return false
+	}
+	return true
+}
+
+// setEncoder replaces the encoder, which is a conflict if another option has already set one
+func (c *Client) setEncoder(option string, encoder Encoder) {
+	if c.options != nil {
+		if c.options.encoderSet {
+			c.invalid("%s conflicts with an encoder set by an earlier option", option)
+			return
+		}
+		c.options.encoderSet = true
+	}
+	c.encoder = encoder
+}
+
+// errOption produces an Option which reports err when applied
+func errOption(err error) Option {
+	return func(c *Client) {
+		c.invalid("%w", err)
+	}
+}
+
+var errNoEncoder = errors.New("GzipRequests needs an encoder, such as EncodeJSON, to be set first")
@@ -0,0 +1,85 @@
+package fourten_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestOptionValidation(t *testing.T) {
+	t.Run("NewE returns a client when options are valid", func(t *testing.T) {
+		client, err := fourten.NewE(fourten.BaseURL(server.URL), fourten.RequestTimeout(time.Second))
+		assert.NilError(t, err)
+
+		_, err = client.GET(ctx, "/ping", nil)
+		assert.Check(t, err)
+	})
+
+	t.Run("NewE collects every invalid option", func(t *testing.T) {
+		client, err := fourten.NewE(
+			fourten.BaseURL(":/:/:"),
+			fourten.RequestTimeout(-time.Second),
+			fourten.MaxConcurrent(0),
+		)
+		assert.Check(t, cmp.Nil(client))
+
+		var optsErr *fourten.OptionsError
+		assert.Assert(t, errors.As(err, &optsErr))
+		assert.Check(t, cmp.Len(optsErr.Errs, 3))
+		assert.Check(t, cmp.ErrorContains(err, "invalid BaseURL"))
+		assert.Check(t, cmp.ErrorContains(err, "RequestTimeout must not be negative"))
+		assert.Check(t, cmp.ErrorContains(err, "MaxConcurrent must be at least 1"))
+	})
+
+	t.Run("the collected errors can be matched and extracted", func(t *testing.T) {
+		_, err := fourten.NewE(fourten.RequestTimeout(-time.Second), fourten.BaseURL(":/:/:"))
+
+		var urlErr *url.Error
+		assert.Assert(t, errors.As(err, &urlErr))
+		assert.Check(t, cmp.Equal(urlErr.URL, ":/:/:"))
+		assert.Check(t, errors.Is(err, urlErr))
+		assert.Check(t, !errors.Is(err, fourten.ErrHTTP))
+	})
+
+	t.Run("conflicting encoders are reported", func(t *testing.T) {
+		_, err := fourten.NewE(fourten.EncodeJSON, fourten.EncodeJSON)
+		assert.Check(t, cmp.ErrorContains(err, "EncodeJSON conflicts"))
+	})
+
+	t.Run("gzip needs an encoder to wrap", func(t *testing.T) {
+		_, err := fourten.NewE(fourten.GzipRequests)
+		assert.Check(t, cmp.ErrorContains(err, "GzipRequests needs an encoder"))
+	})
+
+	t.Run("a derived client may replace its parent's encoder", func(t *testing.T) {
+		parent := fourten.New(fourten.EncodeJSON)
+		_, err := parent.DeriveE(fourten.EncodeJSON)
+		assert.Check(t, err)
+	})
+
+	t.Run("DeriveE leaves the parent untouched when options are invalid", func(t *testing.T) {
+		parent := fourten.New(fourten.BaseURL(server.URL))
+		derived, err := parent.DeriveE(fourten.RetryMaxAttempts(-1), fourten.RateLimit(0, 1))
+		assert.Check(t, cmp.Nil(derived))
+		assert.Check(t, cmp.ErrorContains(err, "RetryMaxAttempts must be at least 0"))
+		assert.Check(t, cmp.ErrorContains(err, "RateLimit rps must be positive"))
+
+		_, err = parent.GET(ctx, "/ping", nil)
+		assert.Check(t, err)
+	})
+
+	t.Run("New and Derive panic on invalid options", func(t *testing.T) {
+		assert.Check(t, cmp.Panics(func() {
+			fourten.New(fourten.TotalTimeout(-time.Second))
+		}))
+		assert.Check(t, cmp.Panics(func() {
+			fourten.New().Derive(fourten.Hedge(-time.Second, 1))
+		}))
+	})
+}
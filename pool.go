@@ -0,0 +1,83 @@
+package fourten
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// MaxIdleConns limits the number of idle connections kept open across all hosts, 0 means no limit
+func MaxIdleConns(n int) Option {
+	return func(c *Client) {
+		if c.validCount("MaxIdleConns", n, 0) {
+			c.ownTransport().MaxIdleConns = n
+		}
+	}
+}
+
+// MaxIdleConnsPerHost limits the number of idle connections kept open to each host.
+// The default of 2 is usually too low for a client making many concurrent requests to one service.
+func MaxIdleConnsPerHost(n int) Option {
+	return func(c *Client) {
+		if c.validCount("MaxIdleConnsPerHost", n, 0) {
+			c.ownTransport().MaxIdleConnsPerHost = n
+		}
+	}
+}
+
+// MaxConnsPerHost limits the total number of connections to each host, including those in use,
+// requests beyond this will wait for a connection to become available. 0 means no limit.
+func MaxConnsPerHost(n int) Option {
+	return func(c *Client) {
+		if c.validCount("MaxConnsPerHost", n, 0) {
+			c.ownTransport().MaxConnsPerHost = n
+		}
+	}
+}
+
+// IdleConnTimeout closes connections which have been idle for longer than d, 0 means no limit
+func IdleConnTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if c.validDuration("IdleConnTimeout", d) {
+			c.ownTransport().IdleConnTimeout = d
+		}
+	}
+}
+
+// DisableKeepAlives uses a fresh connection for every request
+func DisableKeepAlives(c *Client) {
+	c.ownTransport().DisableKeepAlives = true
+}
+
+// ForceHTTP2 attempts to negotiate HTTP/2 over TLS, this is the default
+func ForceHTTP2(c *Client) {
+	t := c.ownTransport()
+	t.ForceAttemptHTTP2 = true
+	t.TLSNextProto = nil
+}
+
+// DisableHTTP2 only ever speaks HTTP/1.1
+func DisableHTTP2(c *Client) {
+	t := c.ownTransport()
+	t.ForceAttemptHTTP2 = false
+	t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+}
+
+// SeparateConnections gives a derived client its own connection pool.
+// Derived clients otherwise share their parent's connections, unless they change any of the connection options.
+func SeparateConnections(c *Client) {
+	c.ownTransport()
+}
+
+// CloseIdleConnections closes any connections which are not currently in use.
+// This also affects any clients which share the same connection pool.
+func (c *Client) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
+// Close releases the client's idle connections, allowing it to be used as an io.Closer.
+// Requests which are in progress are unaffected, and the client remains usable afterwards.
+func (c *Client) Close() error {
+	c.CloseIdleConnections()
+	return nil
+}
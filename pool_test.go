@@ -0,0 +1,83 @@
+package fourten_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestConnectionPooling(t *testing.T) {
+	// countingServer reports how many connections have been opened to it
+	countingServer := func(t *testing.T) (string, *int32) {
+		var conns int32
+		counting := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(200)
+		}))
+		counting.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				atomic.AddInt32(&conns, 1)
+			}
+		}
+		counting.Start()
+		t.Cleanup(counting.Close)
+		return counting.URL, &conns
+	}
+
+	t.Run("connections are reused by default", func(t *testing.T) {
+		url, conns := countingServer(t)
+		client := fourten.New(fourten.BaseURL(url))
+
+		for i := 0; i < 3; i++ {
+			_, err := client.GET(ctx, "/ping", nil)
+			assert.NilError(t, err)
+		}
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(conns), int32(1)))
+	})
+
+	t.Run("keep-alives can be disabled", func(t *testing.T) {
+		url, conns := countingServer(t)
+		client := fourten.New(fourten.BaseURL(url), fourten.DisableKeepAlives)
+
+		for i := 0; i < 3; i++ {
+			_, err := client.GET(ctx, "/ping", nil)
+			assert.NilError(t, err)
+		}
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(conns), int32(3)))
+	})
+
+	t.Run("derived clients share connections unless told otherwise", func(t *testing.T) {
+		url, conns := countingServer(t)
+		parent := fourten.New(fourten.BaseURL(url))
+		shared := parent.Derive(fourten.SetHeader("X-Shared", "yes"))
+		separate := parent.Derive(fourten.SeparateConnections)
+
+		for _, client := range []*fourten.Client{parent, shared, separate} {
+			_, err := client.GET(ctx, "/ping", nil)
+			assert.NilError(t, err)
+		}
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(conns), int32(2)))
+	})
+
+	t.Run("idle connections can be closed", func(t *testing.T) {
+		url, conns := countingServer(t)
+		client := fourten.New(fourten.BaseURL(url))
+
+		_, err := client.GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+		client.CloseIdleConnections()
+		_, err = client.GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, client.Close())
+		_, err = client.GET(ctx, "/ping", nil)
+		assert.NilError(t, err, "client is still usable after Close")
+
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(conns), int32(3)))
+	})
+}
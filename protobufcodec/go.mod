@@ -0,0 +1,14 @@
+module github.com/glenjamin/fourten/protobufcodec
+
+go 1.18
+
+require (
+	github.com/glenjamin/fourten v0.0.0-20261016062755-89c57e78af0d
+	google.golang.org/protobuf v1.33.0
+	gotest.tools/v3 v3.0.2
+)
+
+require (
+	github.com/google/go-cmp v0.5.9 // indirect
+	github.com/pkg/errors v0.8.1 // indirect
+)
@@ -0,0 +1,81 @@
+// Package protobufcodec encodes and decodes fourten requests and responses as Protocol Buffers.
+// It lives in its own module so that the protobuf dependency is only needed by clients which use it.
+package protobufcodec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/glenjamin/fourten"
+)
+
+// ContentType is the content type of the protobuf binary format
+const ContentType = "application/x-protobuf"
+
+// Accept prefers the binary format, but also accepts the JSON mapping of protobuf
+const Accept = ContentType + ", application/json;q=0.5"
+
+// Encode sends request bodies in the protobuf binary format, the input must be a proto.Message
+func Encode(c *fourten.Client) {
+	fourten.EncodeWith(Encoder)(c)
+}
+
+// Decode decodes responses into a proto.Message, preferring the binary format but also accepting
+// the JSON mapping of protobuf for servers which can't produce binary
+func Decode(c *fourten.Client) {
+	fourten.DecodeWith(Decoder, Accept)(c)
+}
+
+// Encoder is the fourten.Encoder used by Encode
+func Encoder(input interface{}) (fourten.RequestEncoding, error) {
+	msg, ok := input.(proto.Message)
+	if !ok {
+		return fourten.RequestEncoding{}, fmt.Errorf("cannot encode %T as protobuf, expected a proto.Message", input)
+	}
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return fourten.RequestEncoding{}, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", ContentType)
+	return fourten.RequestEncoding{
+		ContentLength: int64(len(b)),
+		GetBody: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		},
+		Header: header,
+	}, nil
+}
+
+// Decoder is the fourten.Decoder used by Decode
+func Decoder(contentType string, r io.Reader, target interface{}) error {
+	msg, ok := target.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot decode protobuf into %T, expected a proto.Message", target)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	var unmarshal func([]byte, proto.Message) error
+	switch mediaType {
+	case ContentType, "application/protobuf", "application/vnd.google.protobuf":
+		unmarshal = proto.Unmarshal
+	case "application/json":
+		unmarshal = protojson.Unmarshal
+	default:
+		return fmt.Errorf("expected protobuf or JSON content-type, got %s", contentType)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read: %w", err)
+	}
+	if err := unmarshal(b, msg); err != nil {
+		return fmt.Errorf("failed to decode: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package protobufcodec_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+	"github.com/glenjamin/fourten/protobufcodec"
+)
+
+var ctx = context.Background()
+
+func TestProtobuf(t *testing.T) {
+	var received, accept string
+	services := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		accept = r.Header.Get("Accept")
+		if len(b) > 0 {
+			var in wrapperspb.StringValue
+			if err := proto.Unmarshal(b, &in); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			received = r.Header.Get("Content-Type") + " " + in.GetValue()
+		}
+		var out []byte
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			out, _ = protojson.Marshal(wrapperspb.String("from json"))
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			out = []byte("nope")
+		case "/missing":
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			w.WriteHeader(http.StatusNotFound)
+			out, _ = proto.Marshal(wrapperspb.String("not found"))
+		default:
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			out, _ = proto.Marshal(wrapperspb.String("from binary"))
+		}
+		_, _ = w.Write(out)
+	}))
+	defer services.Close()
+	client := fourten.New(fourten.BaseURL(services.URL), protobufcodec.Encode, protobufcodec.Decode)
+
+	t.Run("encodes and decodes binary messages", func(t *testing.T) {
+		var out wrapperspb.StringValue
+		_, err := client.POST(ctx, "/echo", wrapperspb.String("hello"), &out)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(received, "application/x-protobuf hello"))
+		assert.Check(t, cmp.Equal(accept, "application/x-protobuf, application/json;q=0.5"))
+		assert.Check(t, cmp.Equal(out.GetValue(), "from binary"))
+	})
+
+	t.Run("decodes the JSON mapping", func(t *testing.T) {
+		var out wrapperspb.StringValue
+		_, err := client.GET(ctx, "/json", &out)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(out.GetValue(), "from json"))
+	})
+
+	t.Run("decodes error bodies", func(t *testing.T) {
+		_, err := client.GET(ctx, "/missing", nil)
+		httpErr := fourten.AsHTTPError(err)
+		assert.Assert(t, httpErr != nil)
+		var out wrapperspb.StringValue
+		assert.NilError(t, httpErr.Decode(&out))
+		assert.Check(t, cmp.Equal(out.GetValue(), "not found"))
+	})
+
+	t.Run("rejects other content types", func(t *testing.T) {
+		var out wrapperspb.StringValue
+		_, err := client.GET(ctx, "/text", &out)
+		assert.Check(t, cmp.ErrorContains(err, "expected protobuf or JSON content-type, got text/plain"))
+	})
+
+	t.Run("rejects values which aren't messages", func(t *testing.T) {
+		_, err := client.POST(ctx, "/echo", map[string]string{"a": "b"}, nil)
+		assert.Check(t, cmp.ErrorContains(err, "cannot encode map[string]string as protobuf"))
+
+		var out map[string]string
+		_, err = client.GET(ctx, "/", &out)
+		assert.Check(t, cmp.ErrorContains(err, "cannot decode protobuf into *map[string]string"))
+	})
+}
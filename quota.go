@@ -0,0 +1,157 @@
+package fourten
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quota describes the rate limit reported by a server via RateLimit-* or X-RateLimit-* headers
+type Quota struct {
+	// Limit is the number of requests allowed in each window, or 0 if not reported
+	Limit int
+	// Remaining is the number of requests left in the current window
+	Remaining int
+	// ResetAt is when the window ends and the quota is replenished, or the zero time if not reported
+	ResetAt time.Time
+}
+
+// ResponseQuota reads the quota from a response, if the server included one.
+// A nil response, as returned alongside most errors, has no quota.
+func ResponseQuota(res *http.Response) (Quota, bool) {
+	if res == nil {
+		return Quota{}, false
+	}
+	return parseQuota(res.Header, time.Now())
+}
+
+// AdaptiveRateLimit slows requests down as the quota reported by the server runs out.
+// Once fewer than headroom (as a fraction of the limit) requests remain, the rest are spread out evenly
+// until the quota resets, and once none are left requests wait for the reset.
+// Derived clients share the same quota.
+func AdaptiveRateLimit(headroom float64) Option {
+	return func(c *Client) {
+		if headroom < 0 || headroom > 1 {
+			c.invalid("AdaptiveRateLimit headroom must be between 0 and 1, got %v", headroom)
+			return
+		}
+		c.adaptiveRateLimit = &adaptiveRateLimit{headroom: headroom}
+	}
+}
+
+// DontAdaptRateLimit stops paying attention to the quota reported by the server
+func DontAdaptRateLimit(c *Client) {
+	c.adaptiveRateLimit = nil
+}
+
+// parseQuota understands the IETF draft RateLimit headers, and the older X-RateLimit variety.
+// The reset is usually a number of seconds, but large values are taken to be a unix timestamp.
+func parseQuota(header http.Header, now time.Time) (Quota, bool) {
+	for _, prefix := range []string{"RateLimit-", "X-RateLimit-"} {
+		remaining, ok := firstInt(header.Get(prefix + "Remaining"))
+		if !ok {
+			continue
+		}
+		quota := Quota{Remaining: remaining}
+		quota.Limit, _ = firstInt(header.Get(prefix + "Limit"))
+		if reset, ok := firstInt(header.Get(prefix + "Reset")); ok {
+			if reset > 1e9 {
+				quota.ResetAt = time.Unix(int64(reset), 0)
+			} else {
+				quota.ResetAt = now.Add(time.Duration(reset) * time.Second)
+			}
+		}
+		return quota, true
+	}
+	return Quota{}, false
+}
+
+// firstInt reads the leading number from a header, ignoring any extra parameters such as "100, 100;w=60"
+func firstInt(value string) (int, bool) {
+	if i := strings.IndexAny(value, ",;"); i >= 0 {
+		value = value[:i]
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+type adaptiveRateLimit struct {
+	headroom float64
+
+	mu    sync.Mutex
+	quota Quota
+	known bool
+	next  time.Time
+}
+
+func (a *adaptiveRateLimit) update(quota Quota) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.quota = quota
+	a.known = true
+}
+
+// reserve works out how long to wait before the next request, assuming it will use up some of the quota
+func (a *adaptiveRateLimit) reserve(now time.Time) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.known || a.quota.ResetAt.IsZero() || !now.Before(a.quota.ResetAt) {
+		return 0
+	}
+	if a.quota.Remaining <= 0 {
+		return a.quota.ResetAt.Sub(now)
+	}
+	if float64(a.quota.Remaining) > float64(a.quota.Limit)*a.headroom {
+		a.quota.Remaining--
+		return 0
+	}
+	interval := a.quota.ResetAt.Sub(now) / time.Duration(a.quota.Remaining)
+	start := now
+	if a.next.After(start) {
+		start = a.next
+	}
+	a.next = start.Add(interval)
+	a.quota.Remaining--
+	return start.Sub(now)
+}
+
+// tryReserve uses up some of the quota only if the request can be made without waiting
+func (a *adaptiveRateLimit) tryReserve(now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.known || a.quota.ResetAt.IsZero() || !now.Before(a.quota.ResetAt) {
+		return true
+	}
+	if a.quota.Remaining <= 0 {
+		return false
+	}
+	if float64(a.quota.Remaining) <= float64(a.quota.Limit)*a.headroom {
+		if a.next.After(now) {
+			return false
+		}
+		a.next = now.Add(a.quota.ResetAt.Sub(now) / time.Duration(a.quota.Remaining))
+	}
+	a.quota.Remaining--
+	return true
+}
+
+func (a *adaptiveRateLimit) wait(ctx context.Context) error {
+	delay := a.reserve(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+		return fmt.Errorf("rate limit wait of %v would exceed deadline: %w", delay, context.DeadlineExceeded)
+	}
+	if err := sleep(ctx, delay); err != nil {
+		return fmt.Errorf("stopped waiting for rate limit: %w", err)
+	}
+	return nil
+}
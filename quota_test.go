@@ -0,0 +1,112 @@
+package fourten_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestQuota(t *testing.T) {
+	quotaHeaders := func(limit, remaining, reset string) Headers {
+		return Headers{
+			"RateLimit-Limit":     {limit},
+			"RateLimit-Remaining": {remaining},
+			"RateLimit-Reset":     {reset},
+		}
+	}
+
+	t.Run("quota is available from responses", func(t *testing.T) {
+		var quotas []*fourten.Quota
+		client := fourten.New(fourten.BaseURL(server.URL),
+			fourten.Observe(func(fourten.RequestInfo) fourten.ResponseObserver {
+				return func(res fourten.ResponseInfo) {
+					quotas = append(quotas, res.Quota)
+				}
+			}))
+
+		server.Response.Headers = quotaHeaders("100", "42", "30")
+		res, err := client.GET(ctx, "/limited", nil)
+		assert.NilError(t, err)
+		quota, ok := fourten.ResponseQuota(res)
+		assert.Assert(t, ok)
+		assert.Check(t, cmp.Equal(quota.Limit, 100))
+		assert.Check(t, cmp.Equal(quota.Remaining, 42))
+		assert.Check(t, time.Until(quota.ResetAt) > 29*time.Second)
+
+		_, err = client.GET(ctx, "/unlimited", nil)
+		assert.NilError(t, err)
+
+		assert.Assert(t, cmp.Len(quotas, 2))
+		assert.Check(t, cmp.Equal(quotas[0].Remaining, 42))
+		assert.Check(t, quotas[1] == nil)
+	})
+
+	t.Run("there's no quota without a response", func(t *testing.T) {
+		quota, ok := fourten.ResponseQuota(nil)
+		assert.Check(t, !ok)
+		assert.Check(t, cmp.DeepEqual(quota, fourten.Quota{}))
+	})
+
+	t.Run("quota is available from HTTP errors", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL))
+
+		server.Response.Status = 429
+		server.Response.Headers = Headers{
+			"X-RateLimit-Limit":     {"60"},
+			"X-RateLimit-Remaining": {"0"},
+		}
+		_, err := client.GET(ctx, "/limited", nil)
+		quota, ok := fourten.AsHTTPError(err).Quota()
+		assert.Assert(t, ok)
+		assert.Check(t, cmp.DeepEqual(quota, fourten.Quota{Limit: 60}))
+	})
+
+	t.Run("adaptive rate limit waits for the quota to reset once it runs out", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.AdaptiveRateLimit(0.1))
+
+		server.Response.Headers = quotaHeaders("100", "0", "5")
+		_, err := client.GET(ctx, "/limited", nil)
+		assert.NilError(t, err)
+
+		shortCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err = client.Derive().GET(shortCtx, "/limited", nil)
+		assert.Check(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+		assert.Check(t, cmp.ErrorContains(err, "rate limit"))
+		assert.Check(t, time.Since(start) < 100*time.Millisecond)
+
+		_, err = client.Derive(fourten.DontAdaptRateLimit).GET(shortCtx, "/limited", nil)
+		assert.NilError(t, err)
+	})
+
+	t.Run("adaptive rate limit spreads out the last of the quota", func(t *testing.T) {
+		server.Sticky = true
+		server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("RateLimit-Limit", "100")
+			w.Header().Set("RateLimit-Remaining", "5")
+			w.Header().Set("RateLimit-Reset", "1")
+		})
+		t.Cleanup(func() {
+			server.Sticky = false
+			server.Handler = nil
+		})
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.AdaptiveRateLimit(0.1))
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			_, err := client.GET(ctx, "/limited", nil)
+			assert.NilError(t, err)
+		}
+		// 5 remaining over 1 second means 200ms apart, after the first response told us about it
+		assert.Check(t, time.Since(start) >= 200*time.Millisecond)
+		assert.Check(t, time.Since(start) < time.Second)
+	})
+}
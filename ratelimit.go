@@ -0,0 +1,207 @@
+package fourten
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit limits the client to rps requests per second, allowing bursts of up to burst requests.
+// Each attempt waits for its turn, or until the context is done. Derived clients share the same limit.
+func RateLimit(rps float64, burst int) Option {
+	return func(c *Client) {
+		if !c.validRate("RateLimit", rps, burst) {
+			return
+		}
+		c.rateLimit = newTokenBucket(rps, burst, time.Now())
+	}
+}
+
+// RateLimitPerRoute is like RateLimit, but each target gets its own limit.
+// Targets are compared before any URL parameters are filled in, so "/items/:id" is one route.
+func RateLimitPerRoute(rps float64, burst int) Option {
+	return func(c *Client) {
+		if !c.validRate("RateLimitPerRoute", rps, burst) {
+			return
+		}
+		c.routeRateLimit = &routeRateLimit{
+			rps:     rps,
+			burst:   burst,
+			buckets: make(map[string]*tokenBucket),
+		}
+	}
+}
+
+// DontRateLimit removes any rate limits from the client
+func DontRateLimit(c *Client) {
+	c.rateLimit = nil
+	c.routeRateLimit = nil
+}
+
+func (c *Client) validRate(option string, rps float64, burst int) bool {
+	if rps <= 0 {
+		c.invalid("%s rps must be positive, got %v", option, rps)
+		return false
+	}
+	return c.validCount(option+" burst", burst, 1)
+}
+
+// waitForRateLimit blocks until the request is allowed to go ahead.
+// If a later limit stops the request, tokens already taken from earlier ones are handed back.
+func (c *Client) waitForRateLimit(ctx context.Context, target string) error {
+	var taken []*tokenBucket
+	giveBack := func() {
+		for _, b := range taken {
+			b.unreserve()
+		}
+	}
+	if c.rateLimit != nil {
+		if err := c.rateLimit.wait(ctx); err != nil {
+			return err
+		}
+		taken = append(taken, c.rateLimit)
+	}
+	if c.routeRateLimit != nil {
+		route := c.routeRateLimit.forTarget(target)
+		if err := route.wait(ctx); err != nil {
+			giveBack()
+			return err
+		}
+		taken = append(taken, route)
+	}
+	if c.adaptiveRateLimit != nil {
+		if err := c.adaptiveRateLimit.wait(ctx); err != nil {
+			giveBack()
+			return err
+		}
+	}
+	return nil
+}
+
+// tryRateLimit takes a token for an extra request without waiting, reporting whether one was available
+func (c *Client) tryRateLimit(target string) bool {
+	now := time.Now()
+	var taken []*tokenBucket
+	giveBack := func() {
+		for _, b := range taken {
+			b.unreserve()
+		}
+	}
+	if c.rateLimit != nil {
+		if !c.rateLimit.tryTake(now) {
+			return false
+		}
+		taken = append(taken, c.rateLimit)
+	}
+	if c.routeRateLimit != nil {
+		route := c.routeRateLimit.forTarget(target)
+		if !route.tryTake(now) {
+			giveBack()
+			return false
+		}
+		taken = append(taken, route)
+	}
+	if c.adaptiveRateLimit != nil && !c.adaptiveRateLimit.tryReserve(now) {
+		giveBack()
+		return false
+	}
+	return true
+}
+
+type routeRateLimit struct {
+	rps   float64
+	burst int
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func (r *routeRateLimit) forTarget(target string) *tokenBucket {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.buckets[target]
+	if !ok {
+		b = newTokenBucket(r.rps, r.burst, time.Now())
+		r.buckets[target] = b
+	}
+	return b
+}
+
+// tokenBucket refills at rate tokens per second, up to burst.
+// Tokens can go negative, which queues up waiters in the order they arrived.
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rps float64, burst int, now time.Time) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+// reserve takes a token, and reports how long to wait until it can be used
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// tryTake takes a token only if one is available without waiting
+func (b *tokenBucket) tryTake(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// unreserve hands back a token which won't be used after all
+func (b *tokenBucket) unreserve() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+func (b *tokenBucket) wait(ctx context.Context) error {
+	delay := b.reserve(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+		b.unreserve()
+		return fmt.Errorf("rate limit wait of %v would exceed deadline: %w", delay, context.DeadlineExceeded)
+	}
+	if err := sleep(ctx, delay); err != nil {
+		b.unreserve()
+		return fmt.Errorf("stopped waiting for rate limit: %w", err)
+	}
+	return nil
+}
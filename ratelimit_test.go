@@ -0,0 +1,99 @@
+package fourten_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Run("allows bursts, then waits for tokens", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RateLimit(20, 2))
+
+		start := time.Now()
+		for i := 0; i < 2; i++ {
+			_, err := client.GET(ctx, "/ping", nil)
+			assert.NilError(t, err)
+		}
+		assert.Check(t, time.Since(start) < 40*time.Millisecond, "burst is immediate")
+
+		_, err := client.GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+		assert.Check(t, time.Since(start) >= 40*time.Millisecond, "third request waits for a token")
+	})
+
+	t.Run("gives up if the context would expire first", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RateLimit(0.1, 1))
+		_, err := client.GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+
+		shortCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err = client.GET(shortCtx, "/ping", nil)
+		assert.Check(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+		assert.Check(t, cmp.ErrorContains(err, "rate limit"))
+		assert.Check(t, time.Since(start) < 100*time.Millisecond)
+	})
+
+	t.Run("stops waiting when the context is cancelled", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RateLimit(0.1, 1))
+		_, err := client.GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+		time.AfterFunc(10*time.Millisecond, cancel)
+		_, err = client.GET(cancelCtx, "/ping", nil)
+		assert.Check(t, errors.Is(err, context.Canceled), "%v", err)
+	})
+
+	t.Run("derived clients share the limit", func(t *testing.T) {
+		parent := fourten.New(fourten.BaseURL(server.URL), fourten.RateLimit(0.1, 1))
+		derived := parent.Derive(fourten.SetHeader("X-Derived", "yes"))
+		_, err := parent.GET(ctx, "/ping", nil)
+		assert.NilError(t, err)
+
+		shortCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err = derived.GET(shortCtx, "/ping", nil)
+		assert.Check(t, cmp.ErrorContains(err, "rate limit"))
+
+		_, err = derived.Derive(fourten.DontRateLimit).GET(shortCtx, "/ping", nil)
+		assert.NilError(t, err)
+	})
+
+	t.Run("routes can be limited separately", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RateLimitPerRoute(0.1, 1))
+
+		shortCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err := client.GET(shortCtx, "/items/:id", nil, fourten.Param("id", "1"))
+		assert.NilError(t, err)
+		_, err = client.GET(shortCtx, "/users/:id", nil, fourten.Param("id", "1"))
+		assert.NilError(t, err)
+
+		// the route is the same, even though the parameter isn't
+		_, err = client.GET(shortCtx, "/items/:id", nil, fourten.Param("id", "2"))
+		assert.Check(t, cmp.ErrorContains(err, "rate limit"))
+	})
+
+	t.Run("calls stopped by the route limit don't use up the client-wide limit", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RateLimit(0.1, 2), fourten.RateLimitPerRoute(0.1, 1))
+
+		shortCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err := client.GET(shortCtx, "/items", nil)
+		assert.NilError(t, err)
+		_, err = client.GET(shortCtx, "/items", nil)
+		assert.Check(t, cmp.ErrorContains(err, "rate limit"))
+
+		_, err = client.GET(shortCtx, "/users", nil)
+		assert.NilError(t, err)
+	})
+}
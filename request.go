@@ -0,0 +1,122 @@
+package fourten
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RequestBuilder describes a single Call piece by piece, as an alternative to the positional arguments of Call.
+// Start one with Client.Request, and make the Call with Do or Stream.
+type RequestBuilder struct {
+	client   *Client
+	method   string
+	target   string
+	input    interface{}
+	output   interface{}
+	settings callSettings
+}
+
+// Request starts building a Call to target using method
+func (c *Client) Request(method, target string) *RequestBuilder {
+	return &RequestBuilder{
+		client:   c,
+		method:   method,
+		target:   target,
+		settings: c.callSettings(nil),
+	}
+}
+
+// Param fills in a URL parameter in the target, as with the Param URLModifier
+func (b *RequestBuilder) Param(k, v string) *RequestBuilder {
+	return b.Option(Param(k, v))
+}
+
+// Query adds a querystring parameter, keeping any which are already present
+func (b *RequestBuilder) Query(k, v string) *RequestBuilder {
+	return b.Option(URLModifier(func(u *url.URL) error {
+		values := u.Query()
+		values.Add(k, v)
+		u.RawQuery = values.Encode()
+		return nil
+	}))
+}
+
+// Header sets a header, replacing any value set on the Client
+func (b *RequestBuilder) Header(header, value string) *RequestBuilder {
+	return b.Option(CallHeader(header, value))
+}
+
+// Cookie adds a cookie to the request
+func (b *RequestBuilder) Cookie(name, value string) *RequestBuilder {
+	if b.settings.header == nil {
+		b.settings.header = make(http.Header)
+	}
+	cookie := (&http.Cookie{Name: name, Value: value}).String()
+	if existing := b.settings.header.Get("Cookie"); existing != "" {
+		cookie = existing + "; " + cookie
+	}
+	b.settings.header.Set("Cookie", cookie)
+	return b
+}
+
+// Body sets the input to be encoded as the request body using the Client's encoder
+func (b *RequestBuilder) Body(input interface{}) *RequestBuilder {
+	b.input = input
+	return b
+}
+
+// Into sets the output to decode a successful response into using the Client's decoder
+func (b *RequestBuilder) Into(output interface{}) *RequestBuilder {
+	b.output = output
+	return b
+}
+
+// Option applies any other CallOptions, such as CallTimeout or ExpectStatus
+func (b *RequestBuilder) Option(opts ...CallOption) *RequestBuilder {
+	for _, opt := range opts {
+		opt.applyCall(&b.settings)
+	}
+	return b
+}
+
+// Do makes the Call, following the same rules as Client.Call
+func (b *RequestBuilder) Do(ctx context.Context) (*Response, error) {
+	if b.output != nil && b.settings.decoder == nil {
+		return nil, errors.New("output requested but no decoder configured")
+	}
+	return b.do(ctx, false)
+}
+
+// Stream makes the Call without decoding the response, following the same rules as Client.Stream
+func (b *RequestBuilder) Stream(ctx context.Context) (*Response, error) {
+	if b.output != nil {
+		return nil, errors.New("output cannot be decoded from a stream")
+	}
+	return b.do(ctx, true)
+}
+
+func (b *RequestBuilder) do(ctx context.Context, stream bool) (*Response, error) {
+	start := time.Now()
+	res, err := b.client.call(ctx, b.method, b.target, b.input, b.output, stream, b.settings)
+	if res == nil {
+		return nil, err
+	}
+	return &Response{Response: res, Output: b.output, Elapsed: time.Since(start)}, err
+}
+
+// Response is the outcome of a Call made with a RequestBuilder
+type Response struct {
+	*http.Response
+	// Output is what the response body was decoded into, or nil if it was left for the caller
+	Output interface{}
+	// Elapsed is the time taken by the whole Call, across all attempts
+	Elapsed time.Duration
+}
+
+// Quota reports the rate limit quota the server included with the response, if any
+func (r *Response) Quota() (Quota, bool) {
+	return ResponseQuota(r.Response)
+}
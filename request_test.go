@@ -0,0 +1,98 @@
+package fourten_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestRequestBuilder(t *testing.T) {
+	// echo replies with a JSON description of the request it received
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("RateLimit-Remaining", "9")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"query":  r.URL.RawQuery,
+			"header": r.Header.Get("X-Trace"),
+			"cookie": r.Header.Get("Cookie"),
+			"body":   string(body),
+		})
+	}))
+	defer echo.Close()
+	client := fourten.New(fourten.BaseURL(echo.URL), fourten.EncodeJSON, fourten.DecodeJSON)
+
+	t.Run("builds and decodes a call", func(t *testing.T) {
+		var out map[string]string
+		res, err := client.Request("POST", "/items/:id").
+			Param("id", "123").
+			Query("page", "2").
+			Query("tag", "a").
+			Query("tag", "b").
+			Header("X-Trace", "abc").
+			Cookie("session", "xyz").
+			Cookie("theme", "dark").
+			Body(map[string]int{"n": 1}).
+			Into(&out).
+			Do(ctx)
+		assert.NilError(t, err)
+
+		assert.Check(t, cmp.DeepEqual(out, map[string]string{
+			"method": "POST",
+			"path":   "/items/123",
+			"query":  "page=2&tag=a&tag=b",
+			"header": "abc",
+			"cookie": "session=xyz; theme=dark",
+			"body":   "{\"n\":1}\n",
+		}))
+		assert.Check(t, cmp.Equal(res.StatusCode, http.StatusOK))
+		assert.Check(t, res.Output == &out)
+		assert.Check(t, res.Elapsed > 0)
+		quota, ok := res.Quota()
+		assert.Check(t, ok)
+		assert.Check(t, cmp.Equal(quota.Remaining, 9))
+	})
+
+	t.Run("accepts other call options", func(t *testing.T) {
+		res, err := client.Request("GET", "/missing").
+			Option(fourten.ExpectStatus(http.StatusNotFound), fourten.CallTimeout(time.Second)).
+			Do(ctx)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(res.StatusCode, http.StatusNotFound))
+	})
+
+	t.Run("HTTP errors come with the response", func(t *testing.T) {
+		res, err := client.Request("GET", "/missing").Do(ctx)
+		assert.Check(t, cmp.ErrorContains(err, "HTTP Status 404"))
+		assert.Assert(t, res != nil)
+		assert.Check(t, cmp.Equal(res.StatusCode, http.StatusNotFound))
+	})
+
+	t.Run("streams the body", func(t *testing.T) {
+		res, err := client.Request("GET", "/stream").Stream(ctx)
+		assert.NilError(t, err)
+		defer res.Body.Close()
+		b, err := ioutil.ReadAll(res.Body)
+		assert.Check(t, err)
+		assert.Check(t, cmp.Contains(string(b), `"path":"/stream"`))
+	})
+
+	t.Run("output needs a decoder", func(t *testing.T) {
+		var out map[string]string
+		_, err := client.Derive(fourten.DontDecode).Request("GET", "/").Into(&out).Do(ctx)
+		assert.Check(t, cmp.ErrorContains(err, "no decoder configured"))
+	})
+}
@@ -0,0 +1,309 @@
+package fourten
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Retrier decides whether a failed attempt should be tried again.
+// It is called with the error from each failed attempt, and returns how long to wait before
+// the next attempt, or a negative duration to stop retrying and return the error.
+type Retrier func(err error) time.Duration
+
+type retryPolicy struct {
+	maxAttempts   int
+	maxDuration   time.Duration
+	maxRetryAfter time.Duration
+	backoff       backoff
+	strategy      func() Retrier
+}
+
+type backoff struct {
+	initial    time.Duration
+	max        time.Duration
+	multiplier float64
+	jitter     float64
+}
+
+var defaultBackoff = backoff{
+	initial:    100 * time.Millisecond,
+	max:        2 * time.Second,
+	multiplier: 2,
+	jitter:     0.1,
+}
+
+var defaultRetryPolicy = retryPolicy{
+	maxRetryAfter: 10 * time.Second,
+	backoff:       defaultBackoff,
+}
+
+// RetryMaxAttempts enables retries, making at most n attempts in total for each idempotent request.
+// By default timeouts, refused or reset connections, and 429, 500, 502, 503 and 504 responses are retried.
+func RetryMaxAttempts(n int) Option {
+	return func(c *Client) {
+		if c.validCount("RetryMaxAttempts", n, 0) {
+			c.retry.maxAttempts = n
+		}
+	}
+}
+
+// RetryMaxDuration stops retrying once d has passed since the start of the Call,
+// this applies to both the default and custom retry strategies
+func RetryMaxDuration(d time.Duration) Option {
+	return func(c *Client) {
+		if c.validDuration("RetryMaxDuration", d) {
+			c.retry.maxDuration = d
+		}
+	}
+}
+
+// RetryBackoff configures the exponential backoff between attempts.
+// The first retry waits for initial, and each subsequent retry waits multiplier times longer, up to max.
+// Each delay is then randomly adjusted by up to +/- jitter, expressed as a fraction of the delay.
+func RetryBackoff(initial, max time.Duration, multiplier, jitter float64) Option {
+	return func(c *Client) {
+		if !c.validDuration("RetryBackoff initial", initial) || !c.validDuration("RetryBackoff max", max) {
+			return
+		}
+		if multiplier < 1 || jitter < 0 || jitter > 1 {
+			c.invalid("RetryBackoff multiplier must be at least 1 and jitter between 0 and 1, got %v and %v",
+				multiplier, jitter)
+			return
+		}
+		c.retry.backoff = backoff{
+			initial:    initial,
+			max:        max,
+			multiplier: multiplier,
+			jitter:     jitter,
+		}
+	}
+}
+
+// RetryAfterMax caps how long the default retry strategy will wait when a response includes a Retry-After header.
+// Setting this to 0 ignores the header, and uses the normal backoff instead.
+func RetryAfterMax(d time.Duration) Option {
+	return func(c *Client) {
+		if c.validDuration("RetryAfterMax", d) {
+			c.retry.maxRetryAfter = d
+		}
+	}
+}
+
+// RetryStrategy replaces the default attempt counting and backoff with custom logic.
+// The factory is called once per Call, so the Retrier it returns can safely track state.
+func RetryStrategy(strategy func() Retrier) Option {
+	return func(c *Client) {
+		c.retry.strategy = strategy
+	}
+}
+
+// DontRetry disables all retries, including any custom strategy
+func DontRetry(c *Client) {
+	c.retry = defaultRetryPolicy
+}
+
+// ErrRetryBudgetExhausted is matched by the error from a Call which would have been retried,
+// but wasn't because the retry budget has been spent. The error from the last attempt is also available.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+const retryBudgetWindow = 10 * time.Second
+
+// RetryBudget limits retries across all Calls made by the client and those derived from it,
+// so that retries can't multiply the load on an upstream which is already struggling.
+// Over the last 10 seconds, retries can make up ratio of the original requests,
+// plus minPerSecond retries per second which are always allowed so that quiet clients can still retry.
+func RetryBudget(ratio float64, minPerSecond int) Option {
+	return func(c *Client) {
+		if ratio < 0 {
+			c.invalid("RetryBudget ratio must not be negative, got %v", ratio)
+			return
+		}
+		if !c.validCount("RetryBudget minPerSecond", minPerSecond, 0) {
+			return
+		}
+		c.retryBudget = &retryBudget{
+			ratio:  ratio,
+			min:    minPerSecond * int(retryBudgetWindow/time.Second),
+			window: newRollingWindow(retryBudgetWindow),
+		}
+	}
+}
+
+// DontBudgetRetries removes the retry budget, leaving each Call to its own retry policy
+func DontBudgetRetries(c *Client) {
+	c.retryBudget = nil
+}
+
+type retryBudget struct {
+	ratio float64
+	min   int
+
+	mu sync.Mutex
+	// the window counts all requests, with retries counted as failures
+	window *rollingWindow
+}
+
+// deposit notes an original request, which earns a fraction of a retry
+func (b *retryBudget) deposit(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.window.add(now, false)
+}
+
+// withdraw spends a retry, if there's enough in the budget
+func (b *retryBudget) withdraw(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	total, retries := b.window.counts(now)
+	originals := total - retries
+	if float64(retries+1) > float64(b.min)+b.ratio*float64(originals) {
+		return false
+	}
+	b.window.add(now, true)
+	return true
+}
+
+type retryBudgetError struct {
+	err error
+}
+
+func (e *retryBudgetError) Error() string {
+	return ErrRetryBudgetExhausted.Error() + ": " + e.err.Error()
+}
+
+func (e *retryBudgetError) Is(target error) bool {
+	return target == ErrRetryBudgetExhausted
+}
+
+func (e *retryBudgetError) Unwrap() error {
+	return e.err
+}
+
+// retrier produces the Retrier for a single Call, or nil if retries are disabled
+func (p retryPolicy) retrier() Retrier {
+	if p.strategy != nil {
+		return p.strategy()
+	}
+	if p.maxAttempts <= 1 {
+		return nil
+	}
+	attempts := 1
+	return func(err error) time.Duration {
+		if attempts >= p.maxAttempts || !retryableError(err) {
+			return -1
+		}
+		attempts++
+		if delay, ok := p.retryAfter(err); ok {
+			return delay
+		}
+		return p.backoff.delay(attempts - 1)
+	}
+}
+
+// retryAfter finds the delay requested by the server, if we're paying attention to it
+func (p retryPolicy) retryAfter(err error) (time.Duration, bool) {
+	if p.maxRetryAfter <= 0 {
+		return 0, false
+	}
+	httpErr := AsHTTPError(err)
+	if httpErr == nil {
+		return 0, false
+	}
+	delay, ok := httpErr.RetryAfter()
+	if ok && delay > p.maxRetryAfter {
+		delay = p.maxRetryAfter
+	}
+	return delay, ok
+}
+
+// delay calculates how long to wait before the nth retry
+func (b backoff) delay(n int) time.Duration {
+	d := float64(b.initial) * math.Pow(b.multiplier, float64(n-1))
+	if b.max > 0 && d > float64(b.max) {
+		d = float64(b.max)
+	}
+	if b.jitter > 0 {
+		d += d * b.jitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(d)
+}
+
+// retryableError is the default classification of errors, only transient failures are worth retrying
+func retryableError(err error) bool {
+	if httpErr := AsHTTPError(err); httpErr != nil {
+		switch httpErr.Response.StatusCode {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	// anything else which went wrong without a response, such as a bad URL or certificate, would just happen again
+	return errors.Is(err, ErrTimeout) || errors.Is(err, ErrConnectionRefused) || errors.Is(err, ErrConnectionReset)
+}
+
+// isIdempotent reports whether the method can be safely repeated, as per RFC 7231 section 4.2.2
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// retryDelay consults the retrier, and then checks the resulting delay fits within the time we have left
+func (c *Client) retryDelay(ctx context.Context, retrier Retrier, policy retryPolicy, start time.Time, err error) time.Duration {
+	if retrier == nil || ctx.Err() != nil {
+		return -1
+	}
+	delay := retrier(err)
+	if delay < 0 {
+		return -1
+	}
+	if policy.maxDuration > 0 && time.Since(start)+delay > policy.maxDuration {
+		return -1
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+		return -1
+	}
+	return delay
+}
+
+// parseRetryAfter understands both forms of the Retry-After header, as per RFC 7231 section 7.1.3.
+// A date in the past is treated as no delay at all.
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,314 @@
+package fourten_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestRetries(t *testing.T) {
+	fastBackoff := fourten.RetryBackoff(time.Millisecond, 5*time.Millisecond, 2, 0)
+
+	t.Run("retries are off by default", func(t *testing.T) {
+		requests := respondWithStatuses(t, 500, 200)
+		client := fourten.New(fourten.BaseURL(server.URL))
+
+		_, err := client.GET(ctx, "/flaky", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrHTTP))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(1)))
+	})
+
+	t.Run("retries idempotent requests until they succeed", func(t *testing.T) {
+		requests := respondWithStatuses(t, 500, 503, 200)
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RetryMaxAttempts(3), fastBackoff)
+
+		res, err := client.GET(ctx, "/flaky", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(res.StatusCode, 200))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(3)))
+	})
+
+	t.Run("gives up after max attempts, returning the last error", func(t *testing.T) {
+		requests := respondWithStatuses(t, 500, 502, 504, 200)
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RetryMaxAttempts(3), fastBackoff)
+
+		res, err := client.GET(ctx, "/flaky", nil)
+		assert.Check(t, cmp.ErrorContains(err, "HTTP Status 504"))
+		assert.Check(t, cmp.Equal(res.StatusCode, 504))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(3)))
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		requests := respondWithStatuses(t, 404, 200)
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RetryMaxAttempts(3), fastBackoff)
+
+		_, err := client.GET(ctx, "/missing", nil)
+		assert.Check(t, cmp.ErrorContains(err, "HTTP Status 404"))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(1)))
+	})
+
+	t.Run("does not retry non-idempotent methods", func(t *testing.T) {
+		requests := respondWithStatuses(t, 500, 200)
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RetryMaxAttempts(3), fastBackoff)
+
+		_, err := client.POST(ctx, "/flaky", nil, nil)
+		assert.Check(t, cmp.ErrorContains(err, "HTTP Status 500"))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(1)))
+	})
+
+	t.Run("repeats the request body on each attempt", func(t *testing.T) {
+		var bodies []string
+		requests := respondWithStatuses(t, 500, 200)
+		handler := server.Handler
+		server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(server.Request.Body)
+			bodies = append(bodies, string(body))
+			handler.ServeHTTP(w, r)
+		})
+		client := fourten.New(fourten.BaseURL(server.URL),
+			fourten.EncodeJSON, fourten.RetryMaxAttempts(3), fastBackoff)
+
+		_, err := client.PUT(ctx, "/flaky", map[string]string{"put": "me"}, nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(2)))
+		assert.Check(t, cmp.DeepEqual(bodies, []string{`{"put":"me"}` + "\n", `{"put":"me"}` + "\n"}))
+	})
+
+	t.Run("retries connection errors", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		var attempts int
+		client := fourten.New(fourten.BaseURL(closed.URL), fourten.RetryStrategy(func() fourten.Retrier {
+			return func(err error) time.Duration {
+				if attempts++; attempts >= 3 {
+					return -1
+				}
+				return time.Millisecond
+			}
+		}))
+
+		_, err := client.GET(ctx, "/nowhere", nil)
+		assert.Check(t, cmp.ErrorContains(err, "connection refused"))
+		assert.Check(t, cmp.Equal(attempts, 3))
+	})
+
+	t.Run("retries refused connections by default, but not errors which would happen again", func(t *testing.T) {
+		var attempts int
+		countAttempts := fourten.Observe(func(fourten.RequestInfo) fourten.ResponseObserver {
+			attempts++
+			return nil
+		})
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		client := fourten.New(fourten.RetryMaxAttempts(3), fastBackoff, countAttempts)
+
+		_, err := client.GET(ctx, closed.URL+"/nowhere", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrConnectionRefused), "%v", err)
+		assert.Check(t, cmp.Equal(attempts, 3))
+
+		attempts = 0
+		_, err = client.GET(ctx, "ftp://example.invalid/x", nil)
+		assert.Check(t, cmp.ErrorContains(err, "unsupported protocol scheme"))
+		assert.Check(t, cmp.Equal(attempts, 1))
+	})
+
+	t.Run("custom strategies receive each error", func(t *testing.T) {
+		respondWithStatuses(t, 500, 429, 200)
+		var statuses []int
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RetryStrategy(func() fourten.Retrier {
+			return func(err error) time.Duration {
+				statuses = append(statuses, fourten.AsHTTPError(err).Response.StatusCode)
+				return time.Millisecond
+			}
+		}))
+
+		_, err := client.GET(ctx, "/flaky", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.DeepEqual(statuses, []int{500, 429}))
+	})
+
+	t.Run("stops retrying after max duration", func(t *testing.T) {
+		requests := respondWithStatuses(t, 500, 500, 500, 200)
+		client := fourten.New(fourten.BaseURL(server.URL),
+			fourten.RetryMaxAttempts(10),
+			fourten.RetryBackoff(20*time.Millisecond, time.Second, 2, 0),
+			fourten.RetryMaxDuration(50*time.Millisecond))
+
+		_, err := client.GET(ctx, "/flaky", nil)
+		assert.Check(t, cmp.ErrorContains(err, "HTTP Status 500"))
+		// 20ms + 40ms > 50ms, so the third attempt never happens
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(2)))
+	})
+
+	t.Run("won't wait beyond the context deadline", func(t *testing.T) {
+		requests := respondWithStatuses(t, 500, 200)
+		client := fourten.New(fourten.BaseURL(server.URL),
+			fourten.RetryMaxAttempts(3), fourten.RetryBackoff(time.Minute, time.Minute, 1, 0))
+
+		shortCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		start := time.Now()
+		_, err := client.GET(shortCtx, "/flaky", nil)
+		assert.Check(t, cmp.ErrorContains(err, "HTTP Status 500"))
+		assert.Check(t, time.Since(start) < time.Second)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(1)))
+	})
+
+	t.Run("retries too many requests", func(t *testing.T) {
+		requests := respondWithStatuses(t, 429, 200)
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RetryMaxAttempts(3), fastBackoff)
+
+		_, err := client.GET(ctx, "/busy", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(2)))
+	})
+
+	t.Run("waits as long as Retry-After asks", func(t *testing.T) {
+		requests := respondWithRetryAfter(t, 503, "1")
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RetryMaxAttempts(3), fastBackoff)
+
+		start := time.Now()
+		_, err := client.GET(ctx, "/busy", nil)
+		assert.NilError(t, err)
+		assert.Check(t, time.Since(start) >= time.Second)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(2)))
+	})
+
+	t.Run("caps the Retry-After delay", func(t *testing.T) {
+		date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+		requests := respondWithRetryAfter(t, 429, date)
+		client := fourten.New(fourten.BaseURL(server.URL),
+			fourten.RetryMaxAttempts(3), fastBackoff, fourten.RetryAfterMax(10*time.Millisecond))
+
+		start := time.Now()
+		_, err := client.GET(ctx, "/busy", nil)
+		assert.NilError(t, err)
+		assert.Check(t, time.Since(start) < time.Second)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(2)))
+	})
+
+	t.Run("gives up if Retry-After is beyond the context deadline", func(t *testing.T) {
+		requests := respondWithRetryAfter(t, 503, "5")
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RetryMaxAttempts(3), fastBackoff)
+
+		shortCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		_, err := client.GET(shortCtx, "/busy", nil)
+		assert.Check(t, cmp.ErrorContains(err, "HTTP Status 503"))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(1)))
+	})
+
+	t.Run("custom strategies can use Retry-After", func(t *testing.T) {
+		respondWithRetryAfter(t, 429, "7")
+		var delays []time.Duration
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RetryStrategy(func() fourten.Retrier {
+			return func(err error) time.Duration {
+				delay, ok := fourten.AsHTTPError(err).RetryAfter()
+				assert.Check(t, ok)
+				delays = append(delays, delay)
+				return time.Millisecond
+			}
+		}))
+
+		_, err := client.GET(ctx, "/busy", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.DeepEqual(delays, []time.Duration{7 * time.Second}))
+	})
+
+	t.Run("retry budget limits retries across calls", func(t *testing.T) {
+		requests := respondWithStatuses(t, 500)
+		client := fourten.New(fourten.BaseURL(server.URL),
+			fourten.RetryMaxAttempts(3), fastBackoff, fourten.RetryBudget(1, 0))
+
+		// one original request earns one retry
+		_, err := client.GET(ctx, "/flaky", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrRetryBudgetExhausted), "%v", err)
+		assert.Check(t, cmp.ErrorContains(err, "HTTP Status 500"))
+		assert.Check(t, cmp.Equal(fourten.AsHTTPError(err).Response.StatusCode, 500))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(2)))
+
+		// derived clients share the same budget
+		_, err = client.Derive(fourten.SetHeader("X-Derived", "yes")).GET(ctx, "/flaky", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrRetryBudgetExhausted), "%v", err)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(4)))
+
+		_, err = client.Derive(fourten.DontBudgetRetries).GET(ctx, "/flaky", nil)
+		assert.Check(t, !errors.Is(err, fourten.ErrRetryBudgetExhausted), "%v", err)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(7)))
+	})
+
+	t.Run("retry budget always allows a minimum rate of retries", func(t *testing.T) {
+		requests := respondWithStatuses(t, 500, 200)
+		client := fourten.New(fourten.BaseURL(server.URL),
+			fourten.RetryMaxAttempts(3), fastBackoff, fourten.RetryBudget(0, 1))
+
+		_, err := client.GET(ctx, "/flaky", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(2)))
+	})
+
+	t.Run("derived clients inherit retries, and can opt out", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.RetryMaxAttempts(3), fastBackoff)
+
+		requests := respondWithStatuses(t, 500, 200)
+		_, err := client.Derive().GET(ctx, "/flaky", nil)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(2)))
+
+		requests = respondWithStatuses(t, 500, 200)
+		_, err = client.Derive(fourten.DontRetry).GET(ctx, "/flaky", nil)
+		assert.Check(t, cmp.ErrorContains(err, "HTTP Status 500"))
+		assert.Check(t, cmp.Equal(atomic.LoadInt32(requests), int32(1)))
+	})
+}
+
+// respondWithStatuses makes the server reply with each status in turn, repeating the last one.
+// It returns a counter of the requests received.
+func respondWithStatuses(t *testing.T, statuses ...int) *int32 {
+	t.Helper()
+	var requests int32
+	server.Sticky = true
+	server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&requests, 1))
+		if n > len(statuses) {
+			n = len(statuses)
+		}
+		w.WriteHeader(statuses[n-1])
+	})
+	t.Cleanup(func() {
+		server.Sticky = false
+		server.Handler = nil
+	})
+	return &requests
+}
+
+// respondWithRetryAfter makes the server reply once with the status and Retry-After header, and then succeed.
+// It returns a counter of the requests received.
+func respondWithRetryAfter(t *testing.T, status int, retryAfter string) *int32 {
+	t.Helper()
+	var requests int32
+	server.Sticky = true
+	server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(200)
+	})
+	t.Cleanup(func() {
+		server.Sticky = false
+		server.Handler = nil
+	})
+	return &requests
+}
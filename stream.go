@@ -0,0 +1,132 @@
+package fourten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const defaultStreamIdleTimeout = 30 * time.Second
+
+// StreamIdleTimeout limits how long a single read from a streamed response body can wait for data.
+// The default is 30 seconds, and 0 disables the idle timeout.
+func StreamIdleTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if c.validDuration("StreamIdleTimeout", d) {
+			c.streamIdleTimeout = d
+		}
+	}
+}
+
+// streamAttempt makes a single request, handing back the body without reading it.
+// The request context lives on after we return, and is cancelled when the body is closed.
+func (c *Client) streamAttempt(ctx context.Context, req *http.Request, call callDetails) (*http.Response, error) {
+	attemptCtx, cancel := context.WithCancel(ctx)
+
+	// context.WithTimeout would also cut off the body, so we cancel by hand if the headers are too slow
+	timer := time.AfterFunc(call.timeout, cancel)
+	res, err := c.send(attemptCtx, req)
+	attemptTimedOut := !timer.Stop()
+	if attemptTimedOut && err == nil {
+		// the headers arrived just as the timeout cancelled the request, so the body can't be read
+		res.Body.Close()
+		err = &url.Error{Op: urlErrorOp(req.Method), URL: req.URL.String(), Err: context.Canceled}
+	}
+	if err != nil {
+		cancel()
+		var urlErr *url.Error
+		if attemptTimedOut && errors.As(err, &urlErr) {
+			err = &url.Error{Op: urlErr.Op, URL: urlErr.URL, Err: context.DeadlineExceeded}
+		}
+		return nil, classifyTransport(req, classifyTimeout(ctx, attemptTimedOut, err))
+	}
+
+	res.Body = &streamBody{
+		body:   res.Body,
+		idle:   c.streamIdleTimeout,
+		cancel: cancel,
+	}
+
+	httpErr := coerceHTTPError(res, call.failed)
+	if httpErr == nil {
+		return res, nil
+	}
+
+	// error bodies aren't streamed, so when we have a decoder they can be read up front like in Call
+	if call.decoder != nil {
+		defer res.Body.Close()
+		if err := httpErr.populateBody(call.decoder); err != nil {
+			return res, fmt.Errorf("failed to read error body: %w", err)
+		}
+	}
+	return res, httpErr
+}
+
+// urlErrorOp names the operation in a url.Error the same way net/http does, e.g. "Get"
+func urlErrorOp(method string) string {
+	if method == "" {
+		return "Get"
+	}
+	return method[:1] + strings.ToLower(method[1:])
+}
+
+// streamBody applies the idle timeout to each read, and releases the request context on Close
+type streamBody struct {
+	body   io.ReadCloser
+	idle   time.Duration
+	cancel context.CancelFunc
+
+	idled  int32
+	closed int32
+}
+
+func streamBodyOf(res *http.Response) (*streamBody, bool) {
+	if res == nil {
+		return nil, false
+	}
+	body, ok := res.Body.(*streamBody)
+	return body, ok
+}
+
+// alsoCancel ties the lifetime of another context to the body
+func (b *streamBody) alsoCancel(cancel context.CancelFunc) {
+	// error bodies may already have been read and closed for us
+	if atomic.LoadInt32(&b.closed) == 1 {
+		cancel()
+		return
+	}
+	first := b.cancel
+	b.cancel = func() {
+		first()
+		cancel()
+	}
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	if b.idle <= 0 {
+		return b.body.Read(p)
+	}
+	timer := time.AfterFunc(b.idle, func() {
+		atomic.StoreInt32(&b.idled, 1)
+		b.cancel()
+	})
+	n, err := b.body.Read(p)
+	timer.Stop()
+	if err != nil && atomic.LoadInt32(&b.idled) == 1 {
+		err = fmt.Errorf("stream idle for longer than %v: %w", b.idle, context.DeadlineExceeded)
+	}
+	return n, err
+}
+
+func (b *streamBody) Close() error {
+	atomic.StoreInt32(&b.closed, 1)
+	err := b.body.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,122 @@
+package fourten_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestStream(t *testing.T) {
+	// slowBody sends headers straight away, then trickles out the body.
+	// These handlers outlive the client's interest in them, so they get their own server.
+	slowBody := func(t *testing.T, chunks int, delay time.Duration) string {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(200)
+			for i := 0; i < chunks; i++ {
+				time.Sleep(delay)
+				_, _ = fmt.Fprintf(w, "chunk %d\n", i)
+				w.(http.Flusher).Flush()
+			}
+		}))
+		t.Cleanup(slow.Close)
+		return slow.URL
+	}
+
+	t.Run("request timeout does not apply to reading the body", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(slowBody(t, 3, 50*time.Millisecond)),
+			fourten.RequestTimeout(100*time.Millisecond))
+
+		res, err := client.Stream(ctx, "GET", "/download", nil)
+		assert.NilError(t, err)
+		defer res.Body.Close()
+
+		body, err := ioutil.ReadAll(res.Body)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(string(body), "chunk 0\nchunk 1\nchunk 2\n"))
+	})
+
+	t.Run("request timeout applies while waiting for headers", func(t *testing.T) {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(20 * time.Millisecond)
+		}))
+		defer slow.Close()
+		client := fourten.New(fourten.BaseURL(slow.URL), fourten.RequestTimeout(time.Millisecond))
+
+		_, err := client.Stream(ctx, "GET", "/download", nil)
+		assert.Check(t, cmp.ErrorContains(err, "deadline exceeded"))
+		assert.Check(t, errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("idle timeout applies to each read", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(slowBody(t, 2, 50*time.Millisecond)),
+			fourten.StreamIdleTimeout(10*time.Millisecond))
+
+		res, err := client.Stream(ctx, "GET", "/download", nil)
+		assert.NilError(t, err)
+		defer res.Body.Close()
+
+		_, err = ioutil.ReadAll(res.Body)
+		assert.Check(t, cmp.ErrorContains(err, "idle"))
+		assert.Check(t, errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("closing the body releases the request", func(t *testing.T) {
+		released := make(chan struct{})
+		hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(200)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			close(released)
+		}))
+		defer hanging.Close()
+		client := fourten.New(fourten.BaseURL(hanging.URL))
+
+		res, err := client.Stream(ctx, "GET", "/download", nil)
+		assert.NilError(t, err)
+		assert.NilError(t, res.Body.Close())
+
+		_, err = res.Body.Read(make([]byte, 10))
+		assert.Check(t, err != nil)
+		select {
+		case <-released:
+		case <-time.After(time.Second):
+			t.Error("expected server to see the request end")
+		}
+	})
+
+	t.Run("HTTP errors can be decoded", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL), fourten.DecodeJSON)
+		server.Response = StubResponse{
+			Status:  404,
+			Headers: contentTypeJSON,
+			Body:    `{"error": "not found"}`,
+		}
+
+		_, err := client.Stream(ctx, "GET", "/download", nil)
+		httpErr := fourten.AsHTTPError(err)
+		assert.Assert(t, httpErr != nil)
+		var out map[string]string
+		assert.Check(t, httpErr.Decode(&out))
+		assert.Check(t, cmp.DeepEqual(out, map[string]string{"error": "not found"}))
+	})
+
+	t.Run("HTTP errors without a decoder leave the body readable", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(server.URL))
+		server.Response = StubResponse{Status: 500, Body: "oops"}
+
+		res, err := client.Stream(ctx, "GET", "/download", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrHTTP))
+		assertResponse(t, res, StubResponse{Status: 500, Body: "oops"})
+		assert.Check(t, res.Body.Close())
+	})
+}
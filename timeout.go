@@ -0,0 +1,100 @@
+package fourten
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// These errors identify which timeout caused a request to fail, and can be checked with errors.Is.
+// They will all also match ErrTimeout.
+var (
+	ErrConnectTimeout      = errors.New("connect timeout")
+	ErrTLSHandshakeTimeout = errors.New("TLS handshake timeout")
+	ErrResponseTimeout     = errors.New("response timeout")
+	ErrRequestTimeout      = errors.New("request timeout")
+	ErrTotalTimeout        = errors.New("total timeout")
+)
+
+// ConnectTimeout limits the time taken to establish a TCP connection
+func ConnectTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if c.validDuration("ConnectTimeout", d) {
+			c.ownTransport().DialContext = newDialer(d).DialContext
+		}
+	}
+}
+
+// TLSHandshakeTimeout limits the time taken to perform the TLS handshake on a new connection
+func TLSHandshakeTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if c.validDuration("TLSHandshakeTimeout", d) {
+			c.ownTransport().TLSHandshakeTimeout = d
+		}
+	}
+}
+
+// ResponseTimeout limits the time to first byte,
+// which is the time spent waiting for response headers after the request has been sent
+func ResponseTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if c.validDuration("ResponseTimeout", d) {
+			c.ownTransport().ResponseHeaderTimeout = d
+		}
+	}
+}
+
+// TotalTimeout limits the total time taken by a Call, across all retried attempts
+func TotalTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if c.validDuration("TotalTimeout", d) {
+			c.totalTimeout = d
+		}
+	}
+}
+
+type timeoutError struct {
+	kind error
+	err  error
+}
+
+func (e *timeoutError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *timeoutError) Is(target error) bool {
+	return target == e.kind || target == ErrTimeout
+}
+
+func (e *timeoutError) Unwrap() error {
+	return e.err
+}
+
+// classifyTimeout works out which of our timeouts caused a request to fail, if any.
+// When the parent context is done, that isn't down to the attempt so is left for the caller to deal with.
+func classifyTimeout(parent context.Context, attemptTimedOut bool, err error) error {
+	if parent.Err() != nil {
+		return err
+	}
+	if attemptTimedOut {
+		return &timeoutError{kind: ErrRequestTimeout, err: err}
+	}
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		return err
+	}
+	// net/http doesn't export its timeout errors, so the message is all we have to go on
+	var opErr *net.OpError
+	switch msg := err.Error(); {
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return &timeoutError{kind: ErrConnectTimeout, err: err}
+	case strings.Contains(msg, "TLS handshake timeout"):
+		return &timeoutError{kind: ErrTLSHandshakeTimeout, err: err}
+	case strings.Contains(msg, "timeout awaiting response headers"):
+		return &timeoutError{kind: ErrResponseTimeout, err: err}
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package fourten_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+func TestGranularTimeouts(t *testing.T) {
+	// slowServer gets its own server, as the handlers outlive the client's interest in them
+	slowServer := func(t *testing.T, delay time.Duration, status int) string {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(slow.Close)
+		return slow.URL
+	}
+
+	t.Run("request timeout applies to each attempt", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(slowServer(t, 50*time.Millisecond, 200)),
+			fourten.RequestTimeout(5*time.Millisecond))
+
+		_, err := client.GET(ctx, "/slow", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrRequestTimeout), "%v", err)
+		assert.Check(t, cmp.ErrorContains(err, "deadline exceeded"))
+	})
+
+	t.Run("response timeout limits time to first byte", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(slowServer(t, 50*time.Millisecond, 200)),
+			fourten.ResponseTimeout(5*time.Millisecond))
+
+		_, err := client.GET(ctx, "/slow", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrResponseTimeout), "%v", err)
+		assert.Check(t, !errors.Is(err, fourten.ErrRequestTimeout))
+	})
+
+	t.Run("TLS handshake timeout limits time spent negotiating", func(t *testing.T) {
+		// Accept connections, but never respond to the TLS handshake
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NilError(t, err)
+		defer listener.Close()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+			}
+		}()
+
+		client := fourten.New(fourten.BaseURL("https://"+listener.Addr().String()),
+			fourten.TLSHandshakeTimeout(5*time.Millisecond))
+
+		_, err = client.GET(ctx, "/secure", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrTLSHandshakeTimeout), "%v", err)
+	})
+
+	t.Run("total timeout applies across retries", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(slowServer(t, 20*time.Millisecond, 500)),
+			fourten.RetryMaxAttempts(10),
+			fourten.RetryBackoff(time.Millisecond, time.Millisecond, 1, 0),
+			fourten.TotalTimeout(50*time.Millisecond))
+
+		start := time.Now()
+		_, err := client.GET(ctx, "/slow", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrTotalTimeout), "%v", err)
+		assert.Check(t, time.Since(start) < 100*time.Millisecond)
+	})
+
+	t.Run("total timeout applies to streamed bodies", func(t *testing.T) {
+		client := fourten.New(fourten.BaseURL(slowServer(t, 0, 200)),
+			fourten.TotalTimeout(time.Minute))
+
+		res, err := client.Stream(ctx, "GET", "/slow", nil)
+		assert.NilError(t, err)
+		// The body is still readable once the call returns, which would fail if the context was cancelled
+		_, err = res.Body.Read(make([]byte, 1))
+		assert.Check(t, cmp.Error(err, "EOF"))
+		assert.Check(t, res.Body.Close())
+	})
+
+	t.Run("derived clients can change timeouts without affecting the parent", func(t *testing.T) {
+		url := slowServer(t, 20*time.Millisecond, 200)
+		parent := fourten.New(fourten.BaseURL(url))
+		derived := parent.Derive(fourten.ResponseTimeout(time.Millisecond))
+
+		_, err := derived.GET(ctx, "/slow", nil)
+		assert.Check(t, errors.Is(err, fourten.ErrResponseTimeout), "%v", err)
+
+		_, err = parent.GET(ctx, "/slow", nil)
+		assert.NilError(t, err)
+	})
+}
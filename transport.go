@@ -0,0 +1,38 @@
+package fourten
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// newTransport matches the settings of http.DefaultTransport, but gives each Client its own connections
+func newTransport() *http.Transport {
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           newDialer(30 * time.Second).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+func newDialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+	}
+}
+
+// ownTransport returns the client's transport ready for modification.
+// Derived clients share their parent's transport until they need to change it, at which point they get a copy.
+func (c *Client) ownTransport() *http.Transport {
+	if c.sharedTransport {
+		c.transport = c.transport.Clone()
+		c.httpClient.Transport = c.transport
+		c.sharedTransport = false
+	}
+	return c.transport
+}
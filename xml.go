@@ -0,0 +1,56 @@
+package fourten
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// EncodeXML sends request bodies as XML, including the standard XML declaration
+func EncodeXML(c *Client) {
+	c.setEncoder("EncodeXML", xmlEncoder)
+}
+func xmlEncoder(input interface{}) (RequestEncoding, error) {
+	b := bytes.NewBufferString(xml.Header)
+	if err := xml.NewEncoder(b).Encode(input); err != nil {
+		return RequestEncoding{}, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/xml; charset=utf-8")
+	return RequestEncoding{
+		ContentLength: int64(b.Len()),
+		GetBody: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b.Bytes())), nil
+		},
+		Header: header,
+	}, nil
+}
+
+// DecodeXML decodes responses as XML, accepting application/xml, text/xml and any +xml content type
+func DecodeXML(c *Client) {
+	SetHeader("Accept", "application/xml, text/xml")(c)
+	c.decoder = xmlDecoder
+}
+func xmlDecoder(contentType string, r io.Reader, target interface{}) error {
+	if !isXML(contentType) {
+		return errors.New("expected XML content-type, got " + contentType)
+	}
+	if err := xml.NewDecoder(r).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode: %w", err)
+	}
+	return nil
+}
+
+func isXML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
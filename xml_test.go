@@ -0,0 +1,83 @@
+package fourten_test
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/glenjamin/fourten"
+)
+
+type payment struct {
+	XMLName xml.Name `xml:"payment"`
+	ID      string   `xml:"id,attr"`
+	Amount  int      `xml:"amount"`
+}
+
+type paymentError struct {
+	XMLName xml.Name `xml:"error"`
+	Code    string   `xml:"code"`
+}
+
+func TestXML(t *testing.T) {
+	var received, accept string
+	payments := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		received, accept = r.Header.Get("Content-Type")+" "+string(b), r.Header.Get("Accept")
+		switch r.URL.Path {
+		case "/declined":
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusPaymentRequired)
+			_, _ = w.Write([]byte(`<error><code>declined</code></error>`))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+			_, _ = w.Write([]byte(`<payment id="p1"><amount>100</amount></payment>`))
+		}
+	}))
+	defer payments.Close()
+	client := fourten.New(fourten.BaseURL(payments.URL), fourten.EncodeXML, fourten.DecodeXML)
+
+	t.Run("encodes requests", func(t *testing.T) {
+		_, err := client.POST(ctx, "/payments", payment{ID: "p1", Amount: 100}, nil,
+			fourten.QueryMap(map[string]string{"type": "text/xml"}))
+		assert.NilError(t, err)
+		assert.Check(t, cmp.Equal(received, "application/xml; charset=utf-8 "+xml.Header+
+			`<payment id="p1"><amount>100</amount></payment>`))
+		assert.Check(t, cmp.Equal(accept, "application/xml, text/xml"))
+	})
+
+	for _, contentType := range []string{"application/xml", "text/xml; charset=utf-8", "application/soap+xml"} {
+		t.Run("decodes "+contentType, func(t *testing.T) {
+			var out payment
+			_, err := client.GET(ctx, "/payments/p1", &out,
+				fourten.QueryMap(map[string]string{"type": contentType}))
+			assert.NilError(t, err)
+			assert.Check(t, cmp.Equal(out.ID, "p1"))
+			assert.Check(t, cmp.Equal(out.Amount, 100))
+		})
+	}
+
+	t.Run("rejects other content types", func(t *testing.T) {
+		var out payment
+		_, err := client.GET(ctx, "/json", &out)
+		assert.Check(t, cmp.ErrorContains(err, "expected XML content-type, got application/json"))
+	})
+
+	t.Run("decodes error bodies", func(t *testing.T) {
+		_, err := client.GET(ctx, "/declined", nil)
+		httpErr := fourten.AsHTTPError(err)
+		assert.Assert(t, httpErr != nil)
+
+		var out paymentError
+		assert.NilError(t, httpErr.Decode(&out))
+		assert.Check(t, cmp.Equal(out.Code, "declined"))
+	})
+}